@@ -0,0 +1,165 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+const defaultExpoPushURL = "https://exp.host/--/api/v2/push/send"
+
+// Notifier delivers a triggered alert to the target of its subscription
+type Notifier interface {
+	Notify(ctx context.Context, subscription *model.AlertSubscription, notification model.AlertNotification) error
+}
+
+// WebhookNotifier POSTs the notification as JSON to the subscription's URL
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+func NewWebhookNotifier(client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{client: client}
+}
+
+func (notifier *WebhookNotifier) Notify(ctx context.Context, subscription *model.AlertSubscription, notification model.AlertNotification) error {
+	notificationJSON, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("fail to marshal notification: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", subscription.Target, bytes.NewReader(notificationJSON))
+	if err != nil {
+		return fmt.Errorf("fail to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := notifier.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fail to call webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ExpoNotifier sends a push notification through the Expo push service
+type ExpoNotifier struct {
+	client      *http.Client
+	pushURL     string
+	accessToken string // only needed when push security is enabled on the Expo project
+}
+
+func NewExpoNotifier(client *http.Client, pushURL, accessToken string) *ExpoNotifier {
+	if pushURL == "" {
+		pushURL = defaultExpoPushURL
+	}
+	return &ExpoNotifier{client: client, pushURL: pushURL, accessToken: accessToken}
+}
+
+type expoPushMessage struct {
+	To    string                  `json:"to"`
+	Title string                  `json:"title"`
+	Body  string                  `json:"body"`
+	Sound string                  `json:"sound"`
+	Data  model.AlertNotification `json:"data"`
+}
+
+type expoPushResp struct {
+	Data []struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	} `json:"data"`
+}
+
+func (notifier *ExpoNotifier) Notify(ctx context.Context, subscription *model.AlertSubscription, notification model.AlertNotification) error {
+	title := fmt.Sprintf("Car park %s is filling up", notification.CarParkID)
+	if notification.Condition == model.AlertAbove {
+		title = fmt.Sprintf("Car park %s has lots free", notification.CarParkID)
+	}
+	message := []expoPushMessage{{
+		To:    subscription.Target,
+		Title: title,
+		Body:  fmt.Sprintf("%d of %s lots available", notification.AvailableLots, notification.TotalLots),
+		Sound: "default",
+		Data:  notification,
+	}}
+
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("fail to marshal push message: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", notifier.pushURL, bytes.NewReader(messageJSON))
+	if err != nil {
+		return fmt.Errorf("fail to create push request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if notifier.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+notifier.accessToken)
+	}
+
+	resp, err := notifier.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fail to send push notification: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("fail to read push response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("expo push returned status %d: %s", resp.StatusCode, body)
+	}
+
+	var pushResp expoPushResp
+	if err := json.Unmarshal(body, &pushResp); err != nil {
+		return fmt.Errorf("fail to unmarshal push response: %v", err)
+	}
+	for _, ticket := range pushResp.Data {
+		if ticket.Status != "ok" {
+			return fmt.Errorf("expo push rejected: %s", ticket.Message)
+		}
+	}
+	return nil
+}
+
+// StubNotifier only logs and records the notifications, for local development and tests
+type StubNotifier struct {
+	mu   sync.Mutex
+	sent []model.AlertNotification
+}
+
+func NewStubNotifier() *StubNotifier {
+	return &StubNotifier{}
+}
+
+func (notifier *StubNotifier) Notify(ctx context.Context, subscription *model.AlertSubscription, notification model.AlertNotification) error {
+	log.Printf("Alert %s via %s to %s: car park %s lot %s has %d lots available",
+		notification.SubscriptionID, subscription.Channel, subscription.Target,
+		notification.CarParkID, notification.LotType, notification.AvailableLots)
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	notifier.sent = append(notifier.sent, notification)
+	return nil
+}
+
+// Sent returns a copy of the notifications recorded so far
+func (notifier *StubNotifier) Sent() []model.AlertNotification {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	return append([]model.AlertNotification(nil), notifier.sent...)
+}
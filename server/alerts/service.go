@@ -0,0 +1,183 @@
+package alerts
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+const (
+	maxConcurrentNotifications = 8
+	notifyTimeout              = 10 * time.Second
+)
+
+// Service evaluates the subscriptions against every availability refresh.
+// An alert fires when the available lots cross its threshold between two refreshes,
+// so a subscription keeps notifying on every crossing until it expires
+type Service struct {
+	Store     SubscriptionStore
+	notifiers map[string]Notifier // keyed by subscription channel
+}
+
+func NewService(store SubscriptionStore, notifiers map[string]Notifier) *Service {
+	return &Service{Store: store, notifiers: notifiers}
+}
+
+// NewNotifiers builds the notifier of every channel, "stub" only logs instead of delivering.
+// Webhooks get a client of their own, their targets come from the subscribers
+func NewNotifiers(client *http.Client, mode, expoPushURL, expoAccessToken string) map[string]Notifier {
+	if mode == "stub" {
+		stub := NewStubNotifier()
+		return map[string]Notifier{
+			model.AlertChannelWebhook: stub,
+			model.AlertChannelExpo:    stub,
+		}
+	}
+
+	return map[string]Notifier{
+		model.AlertChannelWebhook: NewWebhookNotifier(newWebhookClient()),
+		model.AlertChannelExpo:    NewExpoNotifier(client, expoPushURL, expoAccessToken),
+	}
+}
+
+func (service *Service) SupportsChannel(channel string) bool {
+	_, ok := service.notifiers[channel]
+	return ok
+}
+
+type pendingNotification struct {
+	subscription *model.AlertSubscription
+	notification model.AlertNotification
+}
+
+// Evaluate checks the changed lots of a refresh against the subscriptions and delivers the triggered alerts
+func (service *Service) Evaluate(ctx context.Context, previous map[string]*model.CarPark, deltas []model.AvailabilityDelta, at time.Time) {
+	if len(deltas) == 0 {
+		return
+	}
+
+	subscriptions, err := service.Store.List(ctx)
+	if err != nil {
+		log.Printf("Error evaluating alerts: %v", err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	byCarPark := make(map[string][]*model.AlertSubscription)
+	byLocation := []*model.AlertSubscription{}
+	for _, subscription := range subscriptions {
+		if !at.Before(subscription.ExpiresAt) {
+			continue
+		}
+		if subscription.CarParkID != "" {
+			byCarPark[subscription.CarParkID] = append(byCarPark[subscription.CarParkID], subscription)
+		} else {
+			byLocation = append(byLocation, subscription)
+		}
+	}
+
+	pending := []pendingNotification{}
+	for _, delta := range deltas {
+		if delta.Removed {
+			continue
+		}
+		previousAvailable, ok := previousAvailableLots(previous, delta.CarParkID, delta.LotType)
+		if !ok {
+			continue
+		}
+		available, err := strconv.Atoi(delta.AvailableLots)
+		if err != nil {
+			continue
+		}
+
+		candidates := byCarPark[delta.CarParkID]
+		for _, subscription := range byLocation {
+			distance := utils.CalculateDistance(subscription.Latitude, subscription.Longitude, delta.Latitude, delta.Longitude)
+			if distance*1000 <= subscription.RadiusMeters {
+				candidates = append(candidates, subscription)
+			}
+		}
+
+		for _, subscription := range candidates {
+			if subscription.LotType != delta.LotType || !crossed(subscription, previousAvailable, available) {
+				continue
+			}
+			pending = append(pending, pendingNotification{
+				subscription: subscription,
+				notification: model.AlertNotification{
+					SubscriptionID: subscription.ID,
+					CarParkID:      delta.CarParkID,
+					LotType:        delta.LotType,
+					Condition:      subscription.Condition,
+					Threshold:      subscription.Threshold,
+					AvailableLots:  available,
+					TotalLots:      delta.TotalLots,
+					Latitude:       delta.Latitude,
+					Longitude:      delta.Longitude,
+					TriggeredAt:    at,
+				},
+			})
+		}
+	}
+
+	if len(pending) > 0 {
+		log.Printf("%d alerts triggered", len(pending))
+		service.deliver(ctx, pending)
+	}
+}
+
+func (service *Service) deliver(ctx context.Context, pending []pendingNotification) {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxConcurrentNotifications)
+
+	for _, p := range pending {
+		notifier, ok := service.notifiers[p.subscription.Channel]
+		if !ok {
+			log.Printf("No notifier for alert channel %s", p.subscription.Channel)
+			continue
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(p pendingNotification) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+			defer cancel()
+			if err := notifier.Notify(notifyCtx, p.subscription, p.notification); err != nil {
+				log.Printf("Error delivering alert %s: %v", p.subscription.ID, err)
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+func previousAvailableLots(previous map[string]*model.CarPark, carParkID, lotType string) (int, bool) {
+	carPark, ok := previous[carParkID]
+	if !ok {
+		return 0, false
+	}
+	lot, ok := carPark.LotDetails[lotType]
+	if !ok {
+		return 0, false
+	}
+	available, err := strconv.Atoi(lot.AvailableLots)
+	return available, err == nil
+}
+
+// crossed reports whether the available lots moved across the threshold in the watched direction
+func crossed(subscription *model.AlertSubscription, previous, current int) bool {
+	if subscription.Condition == model.AlertAbove {
+		return previous < subscription.Threshold && current >= subscription.Threshold
+	}
+	return previous > subscription.Threshold && current <= subscription.Threshold
+}
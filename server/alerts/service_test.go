@@ -0,0 +1,123 @@
+package alerts
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+// memoryStore lists a fixed set of subscriptions, Evaluate only reads them
+type memoryStore struct {
+	subscriptions []*model.AlertSubscription
+}
+
+func (store *memoryStore) Save(ctx context.Context, subscription *model.AlertSubscription) error {
+	store.subscriptions = append(store.subscriptions, subscription)
+	return nil
+}
+
+func (store *memoryStore) Get(ctx context.Context, id string) (*model.AlertSubscription, error) {
+	for _, subscription := range store.subscriptions {
+		if subscription.ID == id {
+			return subscription, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (store *memoryStore) Delete(ctx context.Context, id string) error {
+	return ErrNotFound
+}
+
+func (store *memoryStore) List(ctx context.Context) ([]*model.AlertSubscription, error) {
+	return store.subscriptions, nil
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	const lat, lng = 1.3521, 103.8198
+
+	carParkWith := func(available string) map[string]*model.CarPark {
+		return map[string]*model.CarPark{"A1": {
+			CarParkID: "A1",
+			Latitude:  lat,
+			Longitude: lng,
+			LotDetails: map[string]*model.Lot{
+				model.LotTypeCar: {TotalLots: "100", AvailableLots: available},
+			},
+		}}
+	}
+	subscription := func(condition string, threshold int, expiresAt time.Time) *model.AlertSubscription {
+		return &model.AlertSubscription{
+			ID:        "sub",
+			CarParkID: "A1",
+			LotType:   model.LotTypeCar,
+			Condition: condition,
+			Threshold: threshold,
+			Channel:   model.AlertChannelWebhook,
+			Target:    "https://example.com/hook",
+			ExpiresAt: expiresAt,
+		}
+	}
+	live := now.Add(time.Hour)
+	nearby := func(radiusMeters float64) *model.AlertSubscription {
+		s := subscription(model.AlertAbove, 10, live)
+		s.CarParkID = ""
+		s.Latitude, s.Longitude = lat+0.002, lng // ~220 m north of the car park
+		s.RadiusMeters = radiusMeters
+		return s
+	}
+
+	tests := []struct {
+		name         string
+		subscription *model.AlertSubscription
+		previous     string
+		current      string
+		wantSent     bool
+	}{
+		{"rising past the threshold", subscription(model.AlertAbove, 10, live), "4", "12", true},
+		{"rising exactly to the threshold", subscription(model.AlertAbove, 10, live), "9", "10", true},
+		{"staying above the threshold", subscription(model.AlertAbove, 10, live), "12", "15", false},
+		{"still below the threshold", subscription(model.AlertAbove, 10, live), "4", "9", false},
+		{"about to fill", subscription(model.AlertBelow, 5, live), "8", "3", true},
+		{"already filling", subscription(model.AlertBelow, 5, live), "4", "2", false},
+		{"expired subscription", subscription(model.AlertAbove, 10, now), "4", "12", false},
+		{"car park within the radius", nearby(500), "4", "12", true},
+		{"car park outside the radius", nearby(100), "4", "12", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := NewStubNotifier()
+			service := NewService(&memoryStore{[]*model.AlertSubscription{test.subscription}},
+				map[string]Notifier{model.AlertChannelWebhook: stub})
+
+			deltas := []model.AvailabilityDelta{{
+				CarParkID:     "A1",
+				LotType:       model.LotTypeCar,
+				TotalLots:     "100",
+				AvailableLots: test.current,
+				Latitude:      lat,
+				Longitude:     lng,
+			}}
+			service.Evaluate(context.Background(), carParkWith(test.previous), deltas, now)
+
+			sent := stub.Sent()
+			if !test.wantSent {
+				if len(sent) != 0 {
+					t.Fatalf("got %d notifications, want none", len(sent))
+				}
+				return
+			}
+			if len(sent) != 1 {
+				t.Fatalf("got %d notifications, want 1", len(sent))
+			}
+			if sent[0].SubscriptionID != "sub" || sent[0].CarParkID != "A1" || strconv.Itoa(sent[0].AvailableLots) != test.current {
+				t.Errorf("unexpected notification %+v", sent[0])
+			}
+		})
+	}
+}
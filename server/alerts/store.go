@@ -0,0 +1,133 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/database"
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	alertKeyPrefix = "alert"
+	alertIndexKey  = "alerts" // set of every subscription ID, expired ones are pruned on read
+)
+
+var ErrNotFound = errors.New("alert subscription not found")
+
+// SubscriptionStore keeps the subscriptions, Get and Delete return ErrNotFound for an unknown or expired ID
+type SubscriptionStore interface {
+	Save(ctx context.Context, subscription *model.AlertSubscription) error
+	Get(ctx context.Context, id string) (*model.AlertSubscription, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]*model.AlertSubscription, error)
+}
+
+// Store keeps the subscriptions in Redis, each one expires on its own through the key TTL
+type Store struct{}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+func alertKey(id string) string {
+	return database.Key(alertKeyPrefix, id)
+}
+
+func (store *Store) Save(ctx context.Context, subscription *model.AlertSubscription) error {
+	ttl := time.Until(subscription.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("fail to save alert subscription: already expired")
+	}
+
+	subscriptionJSON, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("fail to marshal alert subscription: %v", err)
+	}
+
+	redisClient := database.GetRedisClient()
+	pipe := redisClient.TxPipeline()
+	pipe.Set(ctx, alertKey(subscription.ID), subscriptionJSON, ttl)
+	pipe.SAdd(ctx, database.Key(alertIndexKey), subscription.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("fail to save alert subscription: %v", err)
+	}
+	return nil
+}
+
+func (store *Store) Get(ctx context.Context, id string) (*model.AlertSubscription, error) {
+	subscriptionJSON, err := database.GetRedisClient().Get(ctx, alertKey(id)).Result()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("fail to get alert subscription: %v", err)
+	}
+
+	var subscription model.AlertSubscription
+	if err := json.Unmarshal([]byte(subscriptionJSON), &subscription); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal alert subscription: %v", err)
+	}
+	return &subscription, nil
+}
+
+func (store *Store) Delete(ctx context.Context, id string) error {
+	redisClient := database.GetRedisClient()
+	pipe := redisClient.TxPipeline()
+	deleted := pipe.Del(ctx, alertKey(id))
+	pipe.SRem(ctx, database.Key(alertIndexKey), id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("fail to delete alert subscription: %v", err)
+	}
+	if deleted.Val() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns every live subscription and drops the IDs whose key has expired from the index
+func (store *Store) List(ctx context.Context) ([]*model.AlertSubscription, error) {
+	redisClient := database.GetRedisClient()
+	ids, err := redisClient.SMembers(ctx, database.Key(alertIndexKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fail to list alert subscriptions: %v", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = alertKey(id)
+	}
+	values, err := redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fail to list alert subscriptions: %v", err)
+	}
+
+	subscriptions := make([]*model.AlertSubscription, 0, len(values))
+	expired := []interface{}{}
+	for i, value := range values {
+		subscriptionJSON, ok := value.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+
+		var subscription model.AlertSubscription
+		if err := json.Unmarshal([]byte(subscriptionJSON), &subscription); err != nil {
+			return nil, fmt.Errorf("fail to unmarshal alert subscription: %v", err)
+		}
+		subscriptions = append(subscriptions, &subscription)
+	}
+
+	if len(expired) > 0 {
+		if err := redisClient.SRem(ctx, database.Key(alertIndexKey), expired...).Err(); err != nil {
+			return nil, fmt.Errorf("fail to prune expired alert subscriptions: %v", err)
+		}
+	}
+	return subscriptions, nil
+}
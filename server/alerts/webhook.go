@@ -0,0 +1,81 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// carrier-grade NAT range, not public although netip does not count it as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublicAddress reports whether a webhook may be delivered to the address,
+// subscribers must never make the server call itself, Redis or anything else on the internal network
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// ValidateWebhookURL accepts https URLs whose host only resolves to public addresses.
+// The addresses are checked again on every delivery, the DNS record may have changed since
+func ValidateWebhookURL(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Scheme != "https" || target.Hostname() == "" || target.User != nil {
+		return fmt.Errorf("target must be an https URL for webhook alerts")
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", target.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("target host of the webhook cannot be resolved")
+	}
+	for _, addr := range addrs {
+		if !isPublicAddress(addr) {
+			return fmt.Errorf("target host of the webhook must be a public address")
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns the client webhooks are delivered with.
+// It is separate from the shared client: it dials no proxy, refuses non public addresses at dial time
+// and does not follow redirects, so a subscriber cannot point it at an internal host
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublicAddress(addrPort.Addr()) {
+				return fmt.Errorf("webhook address %s is not public", address)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"log"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/SC2006-Lab/MobileAppProject/handler"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+func SetupAlertRoutes(router fiber.Router, apiData *data.ApiData) {
+	alertGroup := router.Group("/alerts")
+
+	// subscriptions are anonymous, so each client may only create a few per hour
+	createLimit := limiter.New(limiter.Config{
+		Max:        utils.GetEnvConfig().ALERT_CREATE_LIMIT,
+		Expiration: time.Hour,
+	})
+
+	alertGroup.Post("/", createLimit, func(c *fiber.Ctx) error {
+		log.Println("POST /api/alerts")
+		return handler.CreateAlert(c, apiData)
+	})
+
+	alertGroup.Get("/:id", func(c *fiber.Ctx) error {
+		log.Printf("GET /api/alerts/%s", c.Params("id"))
+		return handler.GetAlert(c, apiData)
+	})
+
+	alertGroup.Delete("/:id", func(c *fiber.Ctx) error {
+		log.Printf("DELETE /api/alerts/%s", c.Params("id"))
+		return handler.DeleteAlert(c, apiData)
+	})
+}
@@ -16,4 +16,15 @@ func SetupCarParkRoutes(router fiber.Router, apiData *data.ApiData) {
 		log.Println("POST /api/carpark/nearby")
 		return handler.GetNearbyCarParks(c, apiData)
 	})
+
+	// registered before /:id so "stream" is not taken as a car park ID
+	carParkGroup.Get("/stream", func(c *fiber.Ctx) error {
+		log.Println("GET /api/carpark/stream")
+		return handler.StreamCarParkAvailability(c, apiData)
+	})
+
+	carParkGroup.Get("/:id/history", func(c *fiber.Ctx) error {
+		log.Printf("GET /api/carpark/%s/history", c.Params("id"))
+		return handler.GetCarParkHistory(c, apiData)
+	})
 }
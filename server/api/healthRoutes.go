@@ -0,0 +1,14 @@
+package api
+
+import (
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/SC2006-Lab/MobileAppProject/handler"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupHealthRoutes(router fiber.Router, apiData *data.ApiData) {
+	// not logged, it is polled by health checks
+	router.Get("/health", func(c *fiber.Ctx) error {
+		return handler.GetHealth(c, apiData)
+	})
+}
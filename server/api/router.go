@@ -9,4 +9,8 @@ func SetupRoutes(app *fiber.App, apiData *data.ApiData) {
 	api := app.Group("/api")
 
 	SetupCarParkRoutes(api, apiData)
+	SetupAlertRoutes(api, apiData)
+	SetupWeatherRoutes(api, apiData)
+	SetupStatsRoutes(api, apiData)
+	SetupHealthRoutes(api, apiData)
 }
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"log"
+
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupStatsRoutes(router fiber.Router, apiData *data.ApiData) {
+	statsGroup := router.Group("/stats")
+
+	statsGroup.Get("/route-cache", func(c *fiber.Ctx) error {
+		log.Println("GET /api/stats/route-cache")
+		return c.JSON(apiData.Routes.Stats())
+	})
+
+	statsGroup.Get("/cache", func(c *fiber.Ctx) error {
+		log.Println("GET /api/stats/cache")
+		return c.JSON(apiData.Cache.Stats())
+	})
+}
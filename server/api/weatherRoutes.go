@@ -0,0 +1,23 @@
+package api
+
+import (
+	"log"
+
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/SC2006-Lab/MobileAppProject/handler"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupWeatherRoutes(router fiber.Router, apiData *data.ApiData) {
+	weatherGroup := router.Group("/weather")
+
+	weatherGroup.Get("/nearest", func(c *fiber.Ctx) error {
+		log.Println("GET /api/weather/nearest")
+		return handler.GetNearestWeather(c, apiData)
+	})
+
+	weatherGroup.Get("/areas", func(c *fiber.Ctx) error {
+		log.Println("GET /api/weather/areas")
+		return handler.GetWeatherAreas(c, apiData)
+	})
+}
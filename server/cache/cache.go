@@ -0,0 +1,16 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key is not cached or has expired
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores short-lived values, losing them only costs the work of computing them again
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
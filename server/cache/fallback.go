@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FallbackStats tells whether the primary cache is currently bypassed and how often it was
+type FallbackStats struct {
+	Degraded      bool      `json:"degraded"`
+	DegradedSince time.Time `json:"degradedSince"`
+	PrimaryErrors uint64    `json:"primaryErrors"` // failed calls to the primary cache
+	FallbackCalls uint64    `json:"fallbackCalls"` // calls served by the fallback cache
+	FallbackSize  int       `json:"fallbackSize"`
+}
+
+// FallbackCache uses the primary cache and switches to the in-memory one as soon as the primary fails.
+// While degraded the primary is left alone, it is tried again once retryAfter has passed since the last failure.
+// Every call to the primary is bounded by callTimeout, so a slow primary counts as failed long before the caller gives up
+type FallbackCache struct {
+	primary     Cache
+	fallback    *MemoryCache
+	retryAfter  time.Duration
+	callTimeout time.Duration
+
+	mu            sync.Mutex
+	degradedSince time.Time // zero while the primary is used
+	retryAt       time.Time
+
+	primaryErrors atomic.Uint64
+	fallbackCalls atomic.Uint64
+}
+
+func NewFallbackCache(primary Cache, fallback *MemoryCache, retryAfter, callTimeout time.Duration) *FallbackCache {
+	return &FallbackCache{primary: primary, fallback: fallback, retryAfter: retryAfter, callTimeout: callTimeout}
+}
+
+func (cache *FallbackCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if cache.usePrimary() {
+		// a call started after the caller gave up says nothing about the primary
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, cache.callTimeout)
+		value, err := cache.primary.Get(callCtx, key)
+		cancel()
+		if err == nil || errors.Is(err, ErrMiss) {
+			cache.recovered()
+			return value, err
+		}
+		cache.failed(err)
+	}
+
+	cache.fallbackCalls.Add(1)
+	return cache.fallback.Get(ctx, key)
+}
+
+func (cache *FallbackCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if cache.usePrimary() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, cache.callTimeout)
+		err := cache.primary.Set(callCtx, key, value, ttl)
+		cancel()
+		if err == nil {
+			cache.recovered()
+			return nil
+		}
+		cache.failed(err)
+	}
+
+	cache.fallbackCalls.Add(1)
+	return cache.fallback.Set(ctx, key, value, ttl)
+}
+
+func (cache *FallbackCache) Stats() FallbackStats {
+	cache.mu.Lock()
+	degradedSince := cache.degradedSince
+	cache.mu.Unlock()
+
+	return FallbackStats{
+		Degraded:      !degradedSince.IsZero(),
+		DegradedSince: degradedSince,
+		PrimaryErrors: cache.primaryErrors.Load(),
+		FallbackCalls: cache.fallbackCalls.Load(),
+		FallbackSize:  cache.fallback.Len(),
+	}
+}
+
+// usePrimary is false while degraded, until it is time to try the primary again
+func (cache *FallbackCache) usePrimary() bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.degradedSince.IsZero() || !time.Now().Before(cache.retryAt)
+}
+
+func (cache *FallbackCache) failed(err error) {
+	cache.primaryErrors.Add(1)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.degradedSince.IsZero() {
+		cache.degradedSince = time.Now()
+		log.Printf("Primary cache unavailable, caching in memory: %v", err)
+	}
+	cache.retryAt = time.Now().Add(cache.retryAfter)
+}
+
+func (cache *FallbackCache) recovered() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if !cache.degradedSince.IsZero() {
+		log.Printf("Primary cache available again after %s", time.Since(cache.degradedSince).Round(time.Second))
+		cache.degradedSince = time.Time{}
+	}
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryCache is an in-process LRU cache, the least recently used entry is evicted once it holds maxEntries
+type MemoryCache struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List // most recently used at the front
+	maxEntries int
+}
+
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: max(maxEntries, 1),
+	}
+}
+
+func (cache *MemoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := element.Value.(*memoryEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		cache.remove(element)
+		return nil, ErrMiss
+	}
+
+	cache.order.MoveToFront(element)
+	return entry.value, nil
+}
+
+func (cache *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		cache.order.MoveToFront(element)
+		return nil
+	}
+
+	cache.entries[key] = cache.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for cache.order.Len() > cache.maxEntries {
+		cache.remove(cache.order.Back())
+	}
+	return nil
+}
+
+func (cache *MemoryCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
+
+func (cache *MemoryCache) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*memoryEntry).key)
+}
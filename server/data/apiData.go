@@ -1,33 +1,197 @@
 package data
 
 import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/alerts"
+	"github.com/SC2006-Lab/MobileAppProject/cache"
+	"github.com/SC2006-Lab/MobileAppProject/database"
 	"github.com/SC2006-Lab/MobileAppProject/external_services"
+	"github.com/SC2006-Lab/MobileAppProject/forecast"
+	"github.com/SC2006-Lab/MobileAppProject/history"
+	"github.com/SC2006-Lab/MobileAppProject/httpclient"
 	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
 )
 
 type ApiData struct {
-	CarPark     map[string]*model.CarPark
-	Weather     map[string]*model.WeatherAreaInfo
-	URAToken    *string
-	OneMapToken *string
+	carPark     map[string]*model.CarPark
+	carParkIdx  *CarParkIndex
+	carParkMu   sync.RWMutex
+	carParkVer  int64 // changes every time carPark is replaced
+	weather     map[string]*model.WeatherAreaInfo
+	weatherMu   sync.RWMutex
+	URAToken    *external_services.TokenManager
+	OneMapToken *external_services.TokenManager
+	HTTPClient  *http.Client // shared by every outbound call
+	Routes      *external_services.RouteCache
+	History     *history.Store // nil when the history directory is unusable
+	Forecaster  *forecast.Forecaster
+	Hub         *AvailabilityHub
+	Readiness   *Readiness
+	Alerts      *alerts.Service
+	Cache       *cache.FallbackCache // Redis, or memory while Redis is unreachable
+
+	// latest successful fetch of every car park source, merged into carPark
+	carParkSources   map[string]external_services.SourceCarParks
+	carParkRefreshMu sync.Mutex // one car park refresh at a time
+	restored         bool       // car parks come from a snapshot and have not been refreshed yet, guarded by carParkRefreshMu
+
+	snapshotPath string // empty when snapshots are disabled
+	snapshotMu   sync.Mutex
+
+	stopRefresh        chan struct{}
+	stopWeatherRefresh chan struct{}
 }
 
 func NewApiData() *ApiData {
+	envConfig := utils.GetEnvConfig()
+	client := httpclient.New(httpclient.Config{
+		UserAgent:  envConfig.HTTP_USER_AGENT,
+		MaxPerHost: envConfig.HTTP_MAX_PER_HOST,
+		MaxRetries: envConfig.HTTP_MAX_RETRIES,
+	})
+
+	responseCache := cache.NewFallbackCache(
+		cache.NewRedisCache(database.GetRedisClient()),
+		cache.NewMemoryCache(envConfig.MEMORY_CACHE_SIZE),
+		envConfig.CACHE_RETRY_INTERVAL,
+		envConfig.CACHE_CALL_TIMEOUT,
+	)
+
 	return &ApiData{
-		CarPark:     model.NewCarPark(),
-		Weather:     model.NewWeatherAreaInfo(),
-		URAToken:    new(string),
-		OneMapToken: new(string),
+		HTTPClient:  client,
+		Cache:       responseCache,
+		carPark:     model.NewCarPark(),
+		carParkIdx:  NewCarParkIndex(nil),
+		weather:     model.NewWeatherAreaInfo(),
+		URAToken:    external_services.NewURATokenManager(client),
+		OneMapToken: external_services.NewOneMapTokenManager(client),
+		Forecaster:  forecast.NewForecaster(),
+		Hub:         NewAvailabilityHub(),
+		Readiness:   NewReadiness(append(external_services.CarParkSourceIDs(), SourceURAToken, SourceWeather, SourceOneMapToken)...),
 	}
 }
 
 func (apiData *ApiData) Init() {
-	external_services.InitCarParkInformation(apiData.CarPark)
-	external_services.InitWeatherInformation(apiData.Weather)
-	external_services.URA_Init(apiData.URAToken)
-	external_services.OneMapInit(apiData.OneMapToken)
+	envConfig := utils.GetEnvConfig()
+	apiData.Routes = external_services.NewRouteCache(envConfig.ROUTE_CACHE_TTL)
+	apiData.Alerts = alerts.NewService(alerts.NewStore(),
+		alerts.NewNotifiers(apiData.HTTPClient, envConfig.ALERT_NOTIFIER, envConfig.EXPO_PUSH_URL, envConfig.EXPO_ACCESS_TOKEN))
+
+	historyStore, err := history.NewStore(envConfig.HISTORY_DIR, envConfig.HISTORY_RETENTION)
+	if err != nil {
+		log.Printf("Availability history disabled: %v", err)
+	} else {
+		apiData.History = historyStore
+		go apiData.loadForecastHistory(envConfig.HISTORY_RETENTION)
+	}
+
+	// With a snapshot the server is ready right away and everything is refreshed in the background
+	apiData.snapshotPath = envConfig.SNAPSHOT_FILE
+	warmStart := apiData.loadSnapshot(envConfig.SNAPSHOT_MAX_AGE)
+
+	// No upstream failure stops the boot, whatever is missing keeps being retried in the background.
+	// On a cold start the URA token is loaded first, so the URA car parks are part of the first car park refresh,
+	// but the other car park sources never wait for it
+	loadURAToken := apiData.recorded(SourceURAToken, func() error { return external_services.URA_Init(apiData.URAToken) })
+	loadOneMapToken := apiData.recorded(SourceOneMapToken, func() error { return external_services.OneMapInit(apiData.OneMapToken) })
+	chains := [][]bootStep{
+		{{[]string{SourceURAToken}, loadURAToken}},
+		{{external_services.CarParkSourceIDs(), apiData.refreshCarParks}},
+		{{[]string{SourceWeather}, apiData.refreshWeather}},
+		{{[]string{SourceOneMapToken}, loadOneMapToken}},
+	}
+	for _, chain := range chains {
+		if warmStart {
+			go apiData.boot(chain...)
+		} else {
+			apiData.boot(chain...)
+		}
+	}
+}
+
+// recorded wraps a load that does not record its own readiness
+func (apiData *ApiData) recorded(source string, load func() error) func() error {
+	return func() error {
+		err := load()
+		apiData.Readiness.Record(source, err)
+		return err
+	}
+}
+
+// GetCarParks returns the current car park snapshot.
+// The map is replaced as a whole on refresh and never modified afterwards, so callers can read it without locking
+func (apiData *ApiData) GetCarParks() map[string]*model.CarPark {
+	apiData.carParkMu.RLock()
+	defer apiData.carParkMu.RUnlock()
+	return apiData.carPark
+}
+
+// GetCarParkIndex returns the spatial index built over the current car park snapshot
+func (apiData *ApiData) GetCarParkIndex() *CarParkIndex {
+	apiData.carParkMu.RLock()
+	defer apiData.carParkMu.RUnlock()
+	return apiData.carParkIdx
+}
+
+// SetCarParks swaps in a freshly fetched car park map together with its spatial index
+func (apiData *ApiData) SetCarParks(carPark map[string]*model.CarPark) {
+	// build outside the lock so readers are never blocked by the rebuild
+	index := NewCarParkIndex(carPark)
+
+	apiData.carParkMu.Lock()
+	defer apiData.carParkMu.Unlock()
+	apiData.carPark = carPark
+	apiData.carParkIdx = index
+	// unique across restarts too, as it keys data cached in Redis
+	apiData.carParkVer = time.Now().UnixNano()
+}
+
+// CarParkVersion identifies the current car park snapshot, data derived from it can be cached under it
+func (apiData *ApiData) CarParkVersion() int64 {
+	apiData.carParkMu.RLock()
+	defer apiData.carParkMu.RUnlock()
+	return apiData.carParkVer
+}
+
+func (apiData *ApiData) getCarParkSources() map[string]external_services.SourceCarParks {
+	apiData.carParkMu.RLock()
+	defer apiData.carParkMu.RUnlock()
+	return apiData.carParkSources
+}
+
+func (apiData *ApiData) setCarParkSources(sources map[string]external_services.SourceCarParks) {
+	apiData.carParkMu.Lock()
+	defer apiData.carParkMu.Unlock()
+	apiData.carParkSources = sources
+}
+
+// recordHistory appends the availability of a freshly fetched snapshot to the history store and the forecaster
+func (apiData *ApiData) recordHistory(carPark map[string]*model.CarPark, at time.Time) {
+	apiData.Forecaster.ObserveSnapshot(carPark, at)
+
+	if apiData.History == nil {
+		return
+	}
+	if err := apiData.History.Append(carPark, at); err != nil {
+		log.Printf("Error recording availability history: %v", err)
+	}
+}
+
+// loadForecastHistory warms up the forecaster from the stored history, it can take a while on a full store
+func (apiData *ApiData) loadForecastHistory(retention time.Duration) {
+	start := time.Now()
+	if err := apiData.Forecaster.LoadHistory(apiData.History, start.Add(-retention)); err != nil {
+		log.Printf("Error loading availability history into the forecaster: %v", err)
+		return
+	}
+	log.Printf("Loaded availability history into the forecaster in %s", time.Since(start))
 }
 
 func (apiData *ApiData) getOneMapToken() string {
-	return *apiData.OneMapToken
+	return apiData.OneMapToken.Token()
 }
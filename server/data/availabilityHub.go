@@ -0,0 +1,167 @@
+package data
+
+import (
+	"log"
+	"sync"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+// A subscriber that has this many refreshes still unread is evicted
+const subscriptionBufferSize = 16
+
+type BoundingBox struct {
+	MinLat, MinLng float64
+	MaxLat, MaxLng float64
+}
+
+func (box *BoundingBox) Contains(lat, lng float64) bool {
+	return lat >= box.MinLat && lat <= box.MaxLat && lng >= box.MinLng && lng <= box.MaxLng
+}
+
+// AvailabilityFilter selects car parks either by ID or by bounding box
+type AvailabilityFilter struct {
+	CarParkIDs map[string]bool
+	BBox       *BoundingBox
+}
+
+func (filter *AvailabilityFilter) Matches(carParkID string, lat, lng float64) bool {
+	if filter.BBox != nil {
+		return filter.BBox.Contains(lat, lng)
+	}
+	return filter.CarParkIDs[carParkID]
+}
+
+type AvailabilitySubscription struct {
+	Filter AvailabilityFilter
+	Events chan []model.AvailabilityDelta
+}
+
+// AvailabilityHub fans the availability deltas of every refresh out to the subscribers interested in them
+type AvailabilityHub struct {
+	mu          sync.RWMutex
+	subscribers map[*AvailabilitySubscription]struct{}
+}
+
+func NewAvailabilityHub() *AvailabilityHub {
+	return &AvailabilityHub{subscribers: make(map[*AvailabilitySubscription]struct{})}
+}
+
+func (hub *AvailabilityHub) Subscribe(filter AvailabilityFilter) *AvailabilitySubscription {
+	subscription := &AvailabilitySubscription{
+		Filter: filter,
+		Events: make(chan []model.AvailabilityDelta, subscriptionBufferSize),
+	}
+
+	hub.mu.Lock()
+	hub.subscribers[subscription] = struct{}{}
+	hub.mu.Unlock()
+	return subscription
+}
+
+func (hub *AvailabilityHub) Unsubscribe(subscription *AvailabilitySubscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if _, ok := hub.subscribers[subscription]; ok {
+		delete(hub.subscribers, subscription)
+		close(subscription.Events)
+	}
+}
+
+func (hub *AvailabilityHub) Subscribers() int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	return len(hub.subscribers)
+}
+
+// Publish sends every subscriber the deltas matching its filter, it never blocks on a slow subscriber.
+// Deltas only make sense on top of the previous ones, so instead of dropping an update a subscriber
+// that is not keeping up is evicted, its Events channel is closed and it has to subscribe again from a fresh snapshot
+func (hub *AvailabilityHub) Publish(deltas []model.AvailabilityDelta) {
+	if len(deltas) == 0 {
+		return
+	}
+
+	overflowed := hub.publish(deltas)
+	if len(overflowed) == 0 {
+		return
+	}
+
+	log.Printf("Evicting %d availability subscribers that are not keeping up", len(overflowed))
+	for _, subscription := range overflowed {
+		hub.Unsubscribe(subscription)
+	}
+}
+
+// publish sends the deltas to every subscriber and returns those whose channel is full
+func (hub *AvailabilityHub) publish(deltas []model.AvailabilityDelta) []*AvailabilitySubscription {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	overflowed := []*AvailabilitySubscription{}
+	for subscription := range hub.subscribers {
+		matched := []model.AvailabilityDelta{}
+		for _, delta := range deltas {
+			if subscription.Filter.Matches(delta.CarParkID, delta.Latitude, delta.Longitude) {
+				matched = append(matched, delta)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		select {
+		case subscription.Events <- matched:
+		default:
+			overflowed = append(overflowed, subscription)
+		}
+	}
+	return overflowed
+}
+
+// diffAvailability lists the lots whose availability changed between two snapshots
+func diffAvailability(previous, current map[string]*model.CarPark) []model.AvailabilityDelta {
+	deltas := []model.AvailabilityDelta{}
+
+	for carParkID, carPark := range current {
+		previousCarPark := previous[carParkID]
+		for lotType, lot := range carPark.LotDetails {
+			if previousCarPark != nil {
+				if previousLot, ok := previousCarPark.LotDetails[lotType]; ok &&
+					previousLot.AvailableLots == lot.AvailableLots && previousLot.TotalLots == lot.TotalLots {
+					continue
+				}
+			}
+			deltas = append(deltas, model.AvailabilityDelta{
+				CarParkID:      carParkID,
+				LotType:        lotType,
+				TotalLots:      lot.TotalLots,
+				AvailableLots:  lot.AvailableLots,
+				UpdateDatetime: lot.UpdateDatetime,
+				Latitude:       carPark.Latitude,
+				Longitude:      carPark.Longitude,
+			})
+		}
+	}
+
+	for carParkID, previousCarPark := range previous {
+		carPark := current[carParkID]
+		for lotType := range previousCarPark.LotDetails {
+			if carPark != nil {
+				if _, ok := carPark.LotDetails[lotType]; ok {
+					continue
+				}
+			}
+			deltas = append(deltas, model.AvailabilityDelta{
+				CarParkID: carParkID,
+				LotType:   lotType,
+				Latitude:  previousCarPark.Latitude,
+				Longitude: previousCarPark.Longitude,
+				Removed:   true,
+			})
+		}
+	}
+
+	return deltas
+}
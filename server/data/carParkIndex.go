@@ -0,0 +1,157 @@
+package data
+
+import (
+	"math"
+	"sort"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+const (
+	// ~1.1 km per cell in latitude, small enough that a 2 km search only touches a handful of cells
+	carParkIndexCellSize = 0.01
+	kmPerDegreeLat       = 111.32
+)
+
+type gridCell struct {
+	Row int
+	Col int
+}
+
+// NearbyCarPark is a car park returned by the index together with its distance (km) from the query point
+type NearbyCarPark struct {
+	CarPark  *model.CarPark
+	Distance float64
+}
+
+// CarParkIndex is a fixed size lat/lon grid over the car parks.
+// It is immutable once built, a refresh builds a new index and swaps it in with the car park map
+type CarParkIndex struct {
+	cells          map[gridCell][]*model.CarPark
+	minRow, maxRow int
+	minCol, maxCol int
+	size           int
+}
+
+func NewCarParkIndex(carParks map[string]*model.CarPark) *CarParkIndex {
+	index := &CarParkIndex{
+		cells:  make(map[gridCell][]*model.CarPark),
+		minRow: math.MaxInt, maxRow: math.MinInt,
+		minCol: math.MaxInt, maxCol: math.MinInt,
+	}
+
+	for _, carPark := range carParks {
+		cell := cellOf(carPark.Latitude, carPark.Longitude)
+		index.cells[cell] = append(index.cells[cell], carPark)
+		index.minRow = min(index.minRow, cell.Row)
+		index.maxRow = max(index.maxRow, cell.Row)
+		index.minCol = min(index.minCol, cell.Col)
+		index.maxCol = max(index.maxCol, cell.Col)
+		index.size++
+	}
+
+	return index
+}
+
+func cellOf(lat, lon float64) gridCell {
+	return gridCell{
+		Row: int(math.Floor(lat / carParkIndexCellSize)),
+		Col: int(math.Floor(lon / carParkIndexCellSize)),
+	}
+}
+
+func (index *CarParkIndex) Size() int {
+	return index.size
+}
+
+// WithinRadius returns every car park within radiusKm of the point, closest first
+func (index *CarParkIndex) WithinRadius(lat, lon, radiusKm float64) []NearbyCarPark {
+	if index.size == 0 || radiusKm <= 0 {
+		return nil
+	}
+
+	dLat := radiusKm / kmPerDegreeLat
+	dLon := radiusKm / (kmPerDegreeLat * math.Cos(lat*math.Pi/180))
+	from := cellOf(lat-dLat, lon-dLon)
+	to := cellOf(lat+dLat, lon+dLon)
+
+	result := []NearbyCarPark{}
+	for row := max(from.Row, index.minRow); row <= min(to.Row, index.maxRow); row++ {
+		for col := max(from.Col, index.minCol); col <= min(to.Col, index.maxCol); col++ {
+			for _, carPark := range index.cells[gridCell{row, col}] {
+				distance := utils.CalculateDistance(lat, lon, carPark.Latitude, carPark.Longitude)
+				if distance <= radiusKm {
+					result = append(result, NearbyCarPark{CarPark: carPark, Distance: distance})
+				}
+			}
+		}
+	}
+
+	sortByDistance(result)
+	return result
+}
+
+// Nearest returns the k closest car parks to the point, closest first.
+// Rings of cells are searched outwards until nothing outside the searched square can be closer than the k-th result
+func (index *CarParkIndex) Nearest(lat, lon float64, k int) []NearbyCarPark {
+	if index.size == 0 || k <= 0 {
+		return nil
+	}
+
+	center := cellOf(lat, lon)
+	// anything outside ring r is at least r*ringKm away, with a margin for the spherical approximations
+	ringKm := 0.99 * carParkIndexCellSize * kmPerDegreeLat * math.Cos(lat*math.Pi/180)
+	maxRing := max(
+		center.Row-index.minRow, index.maxRow-center.Row,
+		center.Col-index.minCol, index.maxCol-center.Col,
+	)
+
+	candidates := []NearbyCarPark{}
+	addCell := func(row, col int) {
+		for _, carPark := range index.cells[gridCell{row, col}] {
+			distance := utils.CalculateDistance(lat, lon, carPark.Latitude, carPark.Longitude)
+			candidates = append(candidates, NearbyCarPark{CarPark: carPark, Distance: distance})
+		}
+	}
+
+	for ring := 0; ring <= maxRing; ring++ {
+		// only the border of the square is new in this ring, and only the part of it overlapping the index
+		fromCol, toCol := max(center.Col-ring, index.minCol), min(center.Col+ring, index.maxCol)
+		for row := max(center.Row-ring, index.minRow); row <= min(center.Row+ring, index.maxRow); row++ {
+			if ring == 0 || row == center.Row-ring || row == center.Row+ring {
+				for col := fromCol; col <= toCol; col++ {
+					addCell(row, col)
+				}
+				continue
+			}
+			if center.Col-ring >= index.minCol {
+				addCell(row, center.Col-ring)
+			}
+			if center.Col+ring <= index.maxCol {
+				addCell(row, center.Col+ring)
+			}
+		}
+
+		if len(candidates) >= k {
+			// later rings only add candidates, so anything past the k-th can be dropped
+			sortByDistance(candidates)
+			candidates = candidates[:k]
+			if candidates[k-1].Distance <= float64(ring)*ringKm {
+				break
+			}
+		}
+	}
+
+	sortByDistance(candidates)
+	return candidates
+}
+
+func sortByDistance(carParks []NearbyCarPark) {
+	sort.Slice(carParks, func(i, j int) bool {
+		if carParks[i].Distance != carParks[j].Distance {
+			return carParks[i].Distance < carParks[j].Distance
+		}
+		return carParks[i].CarPark.CarParkID < carParks[j].CarPark.CarParkID
+	})
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+func bruteForceNearest(carParks map[string]*model.CarPark, lat, lon float64, k int) []NearbyCarPark {
+	all := []NearbyCarPark{}
+	for _, carPark := range carParks {
+		distance := utils.CalculateDistance(lat, lon, carPark.Latitude, carPark.Longitude)
+		all = append(all, NearbyCarPark{CarPark: carPark, Distance: distance})
+	}
+	sortByDistance(all)
+	if len(all) > k {
+		all = all[:k]
+	}
+	return all
+}
+
+func TestCarParkIndexNearest(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	carParks := model.NewCarPark()
+	for i := 0; i < 60; i++ {
+		id := fmt.Sprintf("CP%02d", i)
+		carParks[id] = &model.CarPark{
+			CarParkID: id,
+			Latitude:  1.25 + random.Float64()*0.2,
+			Longitude: 103.65 + random.Float64()*0.35,
+		}
+	}
+	index := NewCarParkIndex(carParks)
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		k        int
+	}{
+		{"closest only", 1.3521, 103.8198, 1},
+		{"a few", 1.3521, 103.8198, 5},
+		{"from the edge of the grid", 1.25, 103.65, 10},
+		{"from outside the grid", 1.1, 104.2, 3},
+		{"every car park", 1.3, 103.9, 60},
+		{"more than the index holds", 1.3, 103.9, 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := index.Nearest(test.lat, test.lon, test.k)
+			want := bruteForceNearest(carParks, test.lat, test.lon, test.k)
+			if len(got) != len(want) {
+				t.Fatalf("got %d car parks, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i].CarPark.CarParkID != want[i].CarPark.CarParkID {
+					t.Errorf("result %d: got %s (%.4f km), want %s (%.4f km)",
+						i, got[i].CarPark.CarParkID, got[i].Distance, want[i].CarPark.CarParkID, want[i].Distance)
+				}
+			}
+		})
+	}
+}
+
+func TestCarParkIndexNearestEmpty(t *testing.T) {
+	if got := NewCarParkIndex(nil).Nearest(1.3521, 103.8198, 5); len(got) != 0 {
+		t.Errorf("got %d car parks from an empty index", len(got))
+	}
+	if got := NewCarParkIndex(map[string]*model.CarPark{"A": {CarParkID: "A", Latitude: 1.3, Longitude: 103.8}}).Nearest(1.3, 103.8, 0); len(got) != 0 {
+		t.Errorf("got %d car parks for k = 0", len(got))
+	}
+}
@@ -0,0 +1,166 @@
+package data
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upstream data the server depends on besides the car park sources, each one becomes ready independently.
+// Car park sources are tracked under their external_services source ID
+const (
+	SourceURAToken    = "uraToken"
+	SourceOneMapToken = "oneMapToken"
+	SourceWeather     = "weather"
+)
+
+const (
+	bootRetryInitialDelay = 5 * time.Second
+	bootRetryMaxDelay     = 5 * time.Minute
+)
+
+type SourceStatus struct {
+	Ready       bool      `json:"ready"` // data has been loaded at least once
+	LastSuccess time.Time `json:"lastSuccess"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastError   string    `json:"lastError,omitempty"` // error of the last attempt, empty if it succeeded
+}
+
+// Readiness tracks which upstream data is loaded, so a degraded server can tell clients what is missing
+type Readiness struct {
+	mu      sync.RWMutex
+	sources map[string]*SourceStatus
+}
+
+func NewReadiness(sources ...string) *Readiness {
+	readiness := &Readiness{sources: make(map[string]*SourceStatus)}
+	for _, source := range sources {
+		readiness.sources[source] = &SourceStatus{}
+	}
+	return readiness
+}
+
+// Record stores the outcome of a load or refresh of the source
+func (readiness *Readiness) Record(source string, err error) {
+	readiness.mu.Lock()
+	defer readiness.mu.Unlock()
+
+	status, ok := readiness.sources[source]
+	if !ok {
+		status = &SourceStatus{}
+		readiness.sources[source] = status
+	}
+
+	status.LastAttempt = time.Now()
+	if err != nil {
+		status.LastError = err.Error()
+		return
+	}
+	status.Ready = true
+	status.LastSuccess = status.LastAttempt
+	status.LastError = ""
+}
+
+// Restored marks the source as ready with data last loaded at the given time, by a previous run
+func (readiness *Readiness) Restored(source string, at time.Time) {
+	readiness.mu.Lock()
+	defer readiness.mu.Unlock()
+
+	readiness.sources[source] = &SourceStatus{Ready: true, LastSuccess: at}
+}
+
+func (readiness *Readiness) Statuses() map[string]SourceStatus {
+	readiness.mu.RLock()
+	defer readiness.mu.RUnlock()
+
+	statuses := make(map[string]SourceStatus, len(readiness.sources))
+	for source, status := range readiness.sources {
+		statuses[source] = *status
+	}
+	return statuses
+}
+
+// SucceededSince reports whether every one of the sources was loaded successfully after since
+func (readiness *Readiness) SucceededSince(since time.Time, sources ...string) bool {
+	readiness.mu.RLock()
+	defer readiness.mu.RUnlock()
+
+	for _, source := range sources {
+		status, ok := readiness.sources[source]
+		if !ok || !status.LastSuccess.After(since) {
+			return false
+		}
+	}
+	return true
+}
+
+func (readiness *Readiness) IsReady(source string) bool {
+	readiness.mu.RLock()
+	defer readiness.mu.RUnlock()
+
+	status, ok := readiness.sources[source]
+	return ok && status.Ready
+}
+
+// Unavailable lists the sources that have never been loaded
+func (readiness *Readiness) Unavailable() []string {
+	readiness.mu.RLock()
+	defer readiness.mu.RUnlock()
+
+	unavailable := []string{}
+	for source, status := range readiness.sources {
+		if !status.Ready {
+			unavailable = append(unavailable, source)
+		}
+	}
+	sort.Strings(unavailable)
+	return unavailable
+}
+
+// bootStep loads sources, load records the readiness of each of them
+type bootStep struct {
+	sources []string
+	load    func() error
+}
+
+func (step bootStep) String() string {
+	return strings.Join(step.sources, ", ")
+}
+
+// boot runs the steps in order, a step only starts once the previous one succeeded.
+// The first attempt of each step happens right away, so on the happy path everything is loaded when boot returns.
+// From the first failure on, the remaining steps carry on in the background, retrying with exponential backoff
+func (apiData *ApiData) boot(steps ...bootStep) {
+	for i, step := range steps {
+		startedAt := time.Now()
+		if err := step.load(); err != nil {
+			log.Printf("Starting without some of %s, loading it in the background", step)
+			go apiData.retryBoot(steps[i:], startedAt, err)
+			return
+		}
+	}
+}
+
+// retryBoot keeps retrying steps[0], which started at startedAt and failed with err, then loads the remaining steps the same way
+func (apiData *ApiData) retryBoot(steps []bootStep, startedAt time.Time, err error) {
+	for i, step := range steps {
+		if i > 0 {
+			startedAt = time.Now()
+			err = step.load()
+		}
+
+		for delay := bootRetryInitialDelay; err != nil; delay = min(delay*2, bootRetryMaxDelay) {
+			log.Printf("Error loading %s, retrying in %s: %v", step, delay, err)
+			time.Sleep(delay)
+			// the periodic refresh may have loaded it in the meantime,
+			// data restored from a snapshot is ready too but still has to be loaded
+			if apiData.Readiness.SucceededSince(startedAt, step.sources...) {
+				break
+			}
+			err = step.load()
+		}
+		log.Printf("Loaded %s", step)
+	}
+}
@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/external_services"
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+const (
+	carParkRefreshTimeout  = 3 * time.Minute
+	alertEvaluationTimeout = time.Minute
+)
+
+// StartCarParkRefresh re-polls LTA DataMall, URA and data.gov.sg every interval in the background.
+// Every source is fetched independently, a source that fails keeps contributing the data of its last successful fetch.
+func (apiData *ApiData) StartCarParkRefresh(interval time.Duration) {
+	apiData.stopRefresh = schedule("Car park", interval, apiData.RefreshCarParks)
+}
+
+// schedule calls refresh every interval in the background until the returned channel is closed.
+// It returns nil when the interval disables the refresh
+func schedule(name string, interval time.Duration, refresh func()) chan struct{} {
+	if interval <= 0 {
+		log.Printf("%s refresh disabled", name)
+		return nil
+	}
+
+	stop := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				log.Printf("%s refresh stopped", name)
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+	log.Printf("%s refresh scheduled every %s", name, interval)
+	return stop
+}
+
+// StopCarParkRefresh stops the background refresh started by StartCarParkRefresh
+func (apiData *ApiData) StopCarParkRefresh() {
+	if apiData.stopRefresh != nil {
+		close(apiData.stopRefresh)
+		apiData.stopRefresh = nil
+	}
+}
+
+func (apiData *ApiData) RefreshCarParks() {
+	if err := apiData.refreshCarParks(); err != nil {
+		log.Printf("Error refreshing Car Park Information, keeping previous data of the failed sources: %v", err)
+	}
+}
+
+// refreshCarParks fetches every source and swaps in the merge of the latest data of each one.
+// It records the readiness of every source and returns an error if any of them failed, even if the others were swapped in
+func (apiData *ApiData) refreshCarParks() error {
+	// the boot and the periodic refresh may overlap
+	apiData.carParkRefreshMu.Lock()
+	defer apiData.carParkRefreshMu.Unlock()
+
+	log.Println("Refreshing Car Park Information")
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), carParkRefreshTimeout)
+	defer cancel()
+
+	fetched, failed := external_services.FetchCarParkSources(ctx, apiData.HTTPClient, apiData.URAToken)
+
+	previousSources := apiData.getCarParkSources()
+	sources := make(map[string]external_services.SourceCarParks, len(previousSources))
+	for id, source := range previousSources {
+		sources[id] = source
+	}
+	errs := []error{}
+	for _, id := range external_services.CarParkSourceIDs() {
+		if source, ok := fetched[id]; ok {
+			sources[id] = source
+			apiData.Readiness.Record(id, nil)
+		} else if err, ok := failed[id]; ok {
+			apiData.Readiness.Record(id, err)
+			errs = append(errs, err)
+		}
+	}
+	// the data of the failed sources is already served, there is nothing new to swap in or record
+	if len(fetched) == 0 {
+		return errors.Join(errs...)
+	}
+
+	merged := make([]external_services.SourceCarParks, 0, len(sources))
+	for _, source := range sources {
+		merged = append(merged, source)
+	}
+	carPark := external_services.MergeCarParks(merged)
+
+	previous := apiData.GetCarParks()
+	apiData.setCarParkSources(sources)
+	apiData.SetCarParks(carPark)
+	apiData.recordHistory(freshAvailability(fetched, carPark), start)
+
+	deltas := diffAvailability(previous, carPark)
+	apiData.Hub.Publish(deltas)
+	// nothing can cross a threshold on the first load, and the first refresh after a warm start
+	// compares against data that may be hours old, so only thresholds crossed from then on are alerted
+	if len(previous) > 0 && !apiData.restored {
+		go apiData.evaluateAlerts(previous, deltas, start)
+	}
+	apiData.restored = false
+	log.Printf("Refreshed %d Car Parks from %d of %d sources in %s, %d lots changed",
+		len(carPark), len(fetched), len(external_services.CarParkSourceIDs()), time.Since(start), len(deltas))
+	apiData.saveSnapshot()
+	return errors.Join(errs...)
+}
+
+// freshAvailability keeps the availability of the served car parks reported by the sources fetched in this refresh,
+// so the data carried forward for a failed source is never recorded again under a new timestamp
+func freshAvailability(fetched map[string]external_services.SourceCarParks, carPark map[string]*model.CarPark) map[string]*model.CarPark {
+	sources := make([]external_services.SourceCarParks, 0, len(fetched))
+	for _, source := range fetched {
+		sources = append(sources, source)
+	}
+
+	fresh := external_services.MergeAvailability(sources)
+	for carParkID := range fresh {
+		if _, ok := carPark[carParkID]; !ok {
+			delete(fresh, carParkID)
+		}
+	}
+	return fresh
+}
+
+// evaluateAlerts runs off the refresh loop so slow notification targets never delay the next refresh
+func (apiData *ApiData) evaluateAlerts(previous map[string]*model.CarPark, deltas []model.AvailabilityDelta, at time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertEvaluationTimeout)
+	defer cancel()
+	apiData.Alerts.Evaluate(ctx, previous, deltas, at)
+}
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/external_services"
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+// Bump whenever the snapshot or one of the models it contains changes incompatibly,
+// snapshots of another version are ignored on boot
+const snapshotVersion = 2
+
+type tokenSnapshot struct {
+	Token  string    `json:"token"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// snapshot is the normalized ApiData written after every successful refresh, so a restart can serve it straight away
+type snapshot struct {
+	Version        int                                         `json:"version"`
+	SavedAt        time.Time                                   `json:"savedAt"`
+	CarParkSources map[string]external_services.SourceCarParks `json:"carParkSources"` // merged again on load
+	Weather        map[string]*model.WeatherAreaInfo           `json:"weather"`
+	URAToken       tokenSnapshot                               `json:"uraToken"`
+	OneMapToken    tokenSnapshot                               `json:"oneMapToken"`
+}
+
+// saveSnapshot writes the current data to the snapshot file, replacing the previous one atomically
+func (apiData *ApiData) saveSnapshot() {
+	if apiData.snapshotPath == "" {
+		return
+	}
+
+	// the car park and weather refreshes both save, one at a time
+	apiData.snapshotMu.Lock()
+	defer apiData.snapshotMu.Unlock()
+
+	current := snapshot{
+		Version:        snapshotVersion,
+		SavedAt:        time.Now(),
+		CarParkSources: apiData.getCarParkSources(),
+		Weather:        apiData.GetWeather(),
+		URAToken:       tokenSnapshot{apiData.URAToken.Token(), apiData.URAToken.Expiry()},
+		OneMapToken:    tokenSnapshot{apiData.OneMapToken.Token(), apiData.OneMapToken.Expiry()},
+	}
+
+	if err := writeSnapshot(apiData.snapshotPath, &current); err != nil {
+		log.Printf("Error saving snapshot: %v", err)
+	}
+}
+
+func writeSnapshot(path string, current *snapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("fail to create snapshot directory: %v", err)
+	}
+
+	// the snapshot holds upstream tokens, so only the server user may read it
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("fail to create snapshot file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(zw).Encode(current); err != nil {
+		tmp.Close()
+		return fmt.Errorf("fail to encode snapshot: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("fail to compress snapshot: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("fail to write snapshot: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("fail to replace snapshot: %v", err)
+	}
+	return nil
+}
+
+func readSnapshot(path string) (*snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("fail to decompress snapshot: %v", err)
+	}
+	defer zr.Close()
+
+	var saved snapshot
+	if err := json.NewDecoder(zr).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("fail to decode snapshot: %v", err)
+	}
+	return &saved, nil
+}
+
+// loadSnapshot restores the data of the last run if its snapshot is recent enough, and reports whether anything was restored
+func (apiData *ApiData) loadSnapshot(maxAge time.Duration) bool {
+	if apiData.snapshotPath == "" {
+		return false
+	}
+
+	saved, err := readSnapshot(apiData.snapshotPath)
+	if os.IsNotExist(err) {
+		log.Println("No snapshot to warm start from")
+		return false
+	} else if err != nil {
+		log.Printf("Error loading snapshot, starting cold: %v", err)
+		return false
+	}
+
+	if saved.Version != snapshotVersion {
+		log.Printf("Ignoring snapshot of version %d, expected %d", saved.Version, snapshotVersion)
+		return false
+	}
+	if age := time.Since(saved.SavedAt); age > maxAge {
+		log.Printf("Ignoring snapshot saved %s ago", age.Round(time.Minute))
+		return false
+	}
+
+	carParkSources := make([]external_services.SourceCarParks, 0, len(saved.CarParkSources))
+	for id, source := range saved.CarParkSources {
+		carParkSources = append(carParkSources, source)
+		apiData.Readiness.Restored(id, saved.SavedAt)
+	}
+	carPark := external_services.MergeCarParks(carParkSources)
+	if len(saved.CarParkSources) > 0 {
+		apiData.setCarParkSources(saved.CarParkSources)
+		apiData.SetCarParks(carPark)
+		apiData.restored = true
+	}
+	if len(saved.Weather) > 0 {
+		apiData.SetWeather(saved.Weather)
+		apiData.Readiness.Restored(SourceWeather, saved.SavedAt)
+	}
+	// tokens are marked ready once their renewal is started by the boot
+	apiData.URAToken.Restore(saved.URAToken.Token, saved.URAToken.Expiry)
+	apiData.OneMapToken.Restore(saved.OneMapToken.Token, saved.OneMapToken.Expiry)
+
+	log.Printf("Warm started from the snapshot saved at %s (%d car parks, %d weather areas)",
+		saved.SavedAt.Format(time.RFC3339), len(carPark), len(saved.Weather))
+	return true
+}
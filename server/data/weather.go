@@ -0,0 +1,85 @@
+package data
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/external_services"
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+const weatherRefreshTimeout = time.Minute
+
+// GetWeather returns the current forecast of every area, keyed by area name.
+// Like the car parks the map is replaced as a whole on refresh, so it is safe to read without locking
+func (apiData *ApiData) GetWeather() map[string]*model.WeatherAreaInfo {
+	apiData.weatherMu.RLock()
+	defer apiData.weatherMu.RUnlock()
+	return apiData.weather
+}
+
+func (apiData *ApiData) SetWeather(weather map[string]*model.WeatherAreaInfo) {
+	apiData.weatherMu.Lock()
+	defer apiData.weatherMu.Unlock()
+	apiData.weather = weather
+}
+
+// NearestWeatherArea returns the forecast area closest to the location and its distance in km, nil when there is no forecast
+func (apiData *ApiData) NearestWeatherArea(lat, lng float64) (*model.WeatherAreaInfo, float64) {
+	var nearest *model.WeatherAreaInfo
+	nearestDistance := math.Inf(1)
+
+	// only ~50 areas, a linear scan is enough
+	for _, area := range apiData.GetWeather() {
+		distance := utils.CalculateDistance(lat, lng, area.Latitude, area.Longitude)
+		if distance < nearestDistance {
+			nearest = area
+			nearestDistance = distance
+		}
+	}
+	return nearest, nearestDistance
+}
+
+// StartWeatherRefresh re-polls the two-hour forecast every interval, NEA updates it every half an hour
+func (apiData *ApiData) StartWeatherRefresh(interval time.Duration) {
+	apiData.stopWeatherRefresh = schedule("Weather", interval, apiData.RefreshWeather)
+}
+
+func (apiData *ApiData) StopWeatherRefresh() {
+	if apiData.stopWeatherRefresh != nil {
+		close(apiData.stopWeatherRefresh)
+		apiData.stopWeatherRefresh = nil
+	}
+}
+
+func (apiData *ApiData) RefreshWeather() {
+	if err := apiData.refreshWeather(); err != nil {
+		log.Printf("Error refreshing Weather Information, keeping previous data: %v", err)
+	}
+}
+
+func (apiData *ApiData) refreshWeather() error {
+	err := apiData.fetchWeather()
+	apiData.Readiness.Record(SourceWeather, err)
+	return err
+}
+
+func (apiData *ApiData) fetchWeather() error {
+	log.Println("Refreshing Weather Information")
+
+	ctx, cancel := context.WithTimeout(context.Background(), weatherRefreshTimeout)
+	defer cancel()
+
+	weather, err := external_services.FetchWeatherInformation(ctx, apiData.HTTPClient)
+	if err != nil {
+		return err
+	}
+
+	apiData.SetWeather(weather)
+	log.Printf("Refreshed Weather Information of %d areas", len(weather))
+	apiData.saveSnapshot()
+	return nil
+}
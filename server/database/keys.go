@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+// SchemaVersion is part of every key, bump it whenever a stored value changes incompatibly.
+// Keys of older versions are removed on startup instead of being misread
+const SchemaVersion = 1
+
+const scanBatchSize = 500
+
+// namespace is the prefix every key of this server lives under, several instances sharing it share their data
+func namespace() string {
+	return utils.GetEnvConfig().REDIS_NAMESPACE
+}
+
+// Key joins the parts under the namespace and schema version, e.g. Key("alert", id) is "<namespace>:v1:alert:<id>"
+func Key(parts ...string) string {
+	return fmt.Sprintf("%s:v%d:%s", namespace(), SchemaVersion, strings.Join(parts, ":"))
+}
+
+// removeStaleSchemas unlinks the keys written by older schema versions of this namespace.
+// Newer versions are left alone, they belong to instances that are already upgraded
+func removeStaleSchemas(ctx context.Context) (int, error) {
+	prefix := namespace() + ":v"
+	return unlinkMatching(ctx, escapePattern(prefix)+"*", func(key string) bool {
+		version, _, found := strings.Cut(strings.TrimPrefix(key, prefix), ":")
+		if !found {
+			return false
+		}
+		number, err := strconv.Atoi(version)
+		return err == nil && number < SchemaVersion
+	})
+}
+
+func unlinkMatching(ctx context.Context, pattern string, match func(key string) bool) (int, error) {
+	removed := 0
+	iter := RedisClient.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+
+	batch := make([]string, 0, scanBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		unlinked, err := RedisClient.Unlink(ctx, batch...).Result()
+		if err != nil {
+			return fmt.Errorf("fail to unlink keys: %v", err)
+		}
+		removed += int(unlinked)
+		batch = batch[:0]
+		return nil
+	}
+
+	for iter.Next(ctx) {
+		if !match(iter.Val()) {
+			continue
+		}
+		batch = append(batch, iter.Val())
+		if len(batch) == scanBatchSize {
+			if err := flush(); err != nil {
+				return removed, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return removed, fmt.Errorf("fail to scan keys: %v", err)
+	}
+	return removed, flush()
+}
+
+// escapePattern escapes the glob characters of a SCAN MATCH pattern
+func escapePattern(literal string) string {
+	var escaped strings.Builder
+	for _, r := range literal {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
@@ -20,18 +20,20 @@ func InitRedis() {
 		DB:       envConfig.REDIS_DB,                // Use default DB
 	})
 
+	// Redis only caches, the server starts without it and caches in memory until it is reachable
 	pong, err := RedisClient.Ping(RedisCtx).Result()
 	if err != nil {
-		log.Fatalf("Error connecting to Redis: %v", err)
+		log.Printf("Error connecting to Redis, starting without it: %v", err)
+		return
 	}
 	log.Printf("Connected to Redis! Pong: %s", pong)
 
-	// Flush the database on startup
-	err = RedisClient.FlushDB(RedisCtx).Err()
+	// Only our own keys of older schemas are removed, the database may be shared with other instances and applications
+	removed, err := removeStaleSchemas(RedisCtx)
 	if err != nil {
-		log.Printf("Error flushing Redis database: %v", err)
+		log.Printf("Error removing stale Redis keys: %v", err)
 	} else {
-		log.Println("Redis database flushed on startup.")
+		log.Printf("Removed %d Redis keys of older schemas under %s.", removed, namespace())
 	}
 }
 
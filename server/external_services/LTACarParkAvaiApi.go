@@ -0,0 +1,156 @@
+package external_services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+const (
+	LTACarParkAvaiURL = "https://datamall2.mytransport.sg/ltaodataservice/CarParkAvailabilityv2"
+	LTAPageSize       = 500 // DataMall returns at most 500 records per call
+	LTAMaxPages       = 100 // guard against an upstream that never returns an empty page
+)
+
+// LTAFetchStats reports how much of CarParkAvailabilityv2 was ingested
+type LTAFetchStats struct {
+	Pages   int
+	Records int
+}
+
+// LTASource is the LTA DataMall CarParkAvailabilityv2 feed (LTA, URA and HDB car parks)
+type LTASource struct {
+	accountKey string
+	resp       *model.LTA_API_CarParkInfo_Resp
+	fetchedAt  string
+}
+
+func NewLTASource(accountKey string) *LTASource {
+	return &LTASource{accountKey: accountKey}
+}
+
+func (source *LTASource) Name() string { return "LTA DataMall" }
+
+func (source *LTASource) ID() string { return LTASourceID }
+
+func (source *LTASource) Priority() int { return LTASourcePriority }
+
+func (source *LTASource) Fetch(ctx context.Context, client *http.Client) error {
+	resp, stats, err := FetchLTACarParkAvailability(ctx, client, source.accountKey)
+	if err != nil {
+		return err
+	}
+
+	source.resp = resp
+	// LTA does not return a per record timestamp, so the fetch time is used instead
+	source.fetchedAt = time.Now().Format("2006-01-02T15:04:05")
+	log.Printf("Fetched Car Park Information from LTA (%d pages, %d records)", stats.Pages, stats.Records)
+	return nil
+}
+
+func (source *LTASource) Normalize() []*model.CarPark {
+	if source.resp == nil {
+		return nil
+	}
+
+	// LTA returns one record per lot type, so records of the same car park are grouped
+	carParks := make(map[string]*model.CarPark)
+	result := make([]*model.CarPark, 0, len(source.resp.Value))
+
+	for _, info := range source.resp.Value {
+		latLon := strings.Split(info.Location, " ")
+		if len(latLon) != 2 {
+			continue
+		}
+		latitude, _ := strconv.ParseFloat(latLon[0], 64)
+		longitude, _ := strconv.ParseFloat(latLon[1], 64)
+
+		carPark, ok := carParks[info.CarparkID]
+		if !ok {
+			carPark = &model.CarPark{
+				CarParkID:  info.CarparkID,
+				Address:    info.Development,
+				Longitude:  longitude,
+				Latitude:   latitude,
+				LotDetails: make(map[string]*model.Lot),
+			}
+
+			// URA car parks can be of any type, so only LTA ones get one
+			if info.Agency == "LTA" {
+				carPark.CarParkType = "MULTI-STOREY CAR PARK"
+			}
+
+			carParks[info.CarparkID] = carPark
+			result = append(result, carPark)
+		}
+
+		carPark.LotDetails[info.LotType] = &model.Lot{
+			AvailableLots:  strconv.Itoa(info.AvailableLots),
+			UpdateDatetime: source.fetchedAt,
+		}
+	}
+
+	return result
+}
+
+// FetchLTACarParkAvailability follows the DataMall $skip paging until an empty page is returned
+// and merges every page into a single response
+func FetchLTACarParkAvailability(ctx context.Context, client *http.Client, accountKey string) (*model.LTA_API_CarParkInfo_Resp, LTAFetchStats, error) {
+	var stats LTAFetchStats
+	merged := &model.LTA_API_CarParkInfo_Resp{}
+
+	for skip := 0; stats.Pages < LTAMaxPages; skip += LTAPageSize {
+		page, err := fetchLTACarParkAvailabilityPage(ctx, client, accountKey, skip)
+		if err != nil {
+			return nil, stats, fmt.Errorf("fail to fetch LTA page at $skip=%d: %v", skip, err)
+		}
+
+		if len(page.Value) == 0 {
+			break
+		}
+
+		stats.Pages++
+		stats.Records += len(page.Value)
+		merged.Odata_Metadata = page.Odata_Metadata
+		merged.Value = append(merged.Value, page.Value...)
+
+		// a short page means there is nothing left to skip to
+		if len(page.Value) < LTAPageSize {
+			break
+		}
+	}
+
+	if stats.Pages == LTAMaxPages {
+		log.Printf("LTA Car Park Availability stopped after %d pages, results may be incomplete", LTAMaxPages)
+	}
+
+	return merged, stats, nil
+}
+
+func fetchLTACarParkAvailabilityPage(ctx context.Context, client *http.Client, accountKey string, skip int) (*model.LTA_API_CarParkInfo_Resp, error) {
+	url := LTACarParkAvaiURL
+	if skip > 0 {
+		url = fmt.Sprintf("%s?$skip=%d", LTACarParkAvaiURL, skip)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request: %v", err)
+	}
+
+	req.Header.Add("AccountKey", accountKey)
+	req.Header.Add("Content-Type", "application/json")
+
+	var page model.LTA_API_CarParkInfo_Resp
+	if err := fetchJSON(client, req, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
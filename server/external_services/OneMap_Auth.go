@@ -2,18 +2,60 @@ package external_services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/SC2006-Lab/MobileAppProject/model"
 	"github.com/SC2006-Lab/MobileAppProject/utils"
 )
 
-func OneMapInit(token *string) {
+const (
+	oneMapTokenURL = "https://www.onemap.gov.sg/api/auth/post/getToken"
+	// OneMap tokens live for about 3 days, renew well before that
+	oneMapTokenRefreshAhead = 6 * time.Hour
+	oneMapTokenDefaultTTL   = 72 * time.Hour
+)
+
+var ErrOneMapUnauthorized = errors.New("OneMap token rejected")
+
+func NewOneMapTokenManager(client *http.Client) *TokenManager {
+	return &TokenManager{
+		name: "OneMap",
+		fetch: func(ctx context.Context) (string, time.Time, error) {
+			return fetchOneMapToken(ctx, client)
+		},
+		renewAt: func(expiry time.Time) time.Time {
+			return expiry.Add(-oneMapTokenRefreshAhead)
+		},
+	}
+}
+
+// OneMapInit gets the first token, unless one was restored, and starts its background renewal.
+// Nothing is started if getting the token fails
+func OneMapInit(tokens *TokenManager) error {
+	if tokens.Token() != "" {
+		log.Println("Using restored OneMap Token")
+	} else {
+		log.Println("Getting OneMap Token")
+		if err := tokens.renew(); err != nil {
+			return fmt.Errorf("failed to get OneMap token: %v", err)
+		}
+		log.Println("OneMap Token retrieved successfully")
+	}
+
+	tokens.Start()
+	return nil
+}
+
+func fetchOneMapToken(ctx context.Context, client *http.Client) (string, time.Time, error) {
 	envConfig := utils.GetEnvConfig()
-	log.Println("Getting OneMap Token")
 	reqPayload := map[string]string{
 		"email":    envConfig.ONEMAP_EMAIL,
 		"password": envConfig.ONEMAP_PASSWORD,
@@ -22,33 +64,48 @@ func OneMapInit(token *string) {
 	// Marshall the request payload to JSON
 	reqPayloadBytes, err := json.Marshal(reqPayload)
 	if err != nil {
-		log.Fatal("Failed to marshal request payload:", err)
+		return "", time.Time{}, fmt.Errorf("failed to marshal request payload: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://www.onemap.gov.sg/api/auth/post/getToken", bytes.NewBuffer(reqPayloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", oneMapTokenURL, bytes.NewBuffer(reqPayloadBytes))
 	if err != nil {
-		log.Fatal("Failed to create request:", err)
+		return "", time.Time{}, fmt.Errorf("failed to create request: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatal("Failed to send request:", err)
+		return "", time.Time{}, fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatal("Failed to read response body:", err)
+		return "", time.Time{}, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	var OneMap_Resp model.OneMap_Resp
 	err = json.Unmarshal(body, &OneMap_Resp)
 	if err != nil {
-		log.Fatal("Failed to unmarshal JSON:", err)
+		return "", time.Time{}, fmt.Errorf("failed to unmarshal JSON: %v", err)
 	}
+	if OneMap_Resp.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("no access token in response")
+	}
+
+	return OneMap_Resp.AccessToken, parseOneMapExpiry(OneMap_Resp.ExpiryTimeStamp), nil
+}
 
-	*token = OneMap_Resp.AccessToken
-	log.Println("OneMap Token retrieved successfully")
+// parseOneMapExpiry reads the unix timestamp (seconds) OneMap returns as a string
+func parseOneMapExpiry(expiryTimeStamp string) time.Time {
+	seconds, err := strconv.ParseInt(expiryTimeStamp, 10, 64)
+	if err != nil || seconds <= 0 {
+		log.Printf("Unknown OneMap token expiry %q, assuming %s", expiryTimeStamp, oneMapTokenDefaultTTL)
+		return time.Now().Add(oneMapTokenDefaultTTL)
+	}
+	return time.Unix(seconds, 0)
 }
@@ -0,0 +1,211 @@
+package external_services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+const URADataServiceURL = "https://eservice.ura.gov.sg/uraDataService/invokeUraDS/v1"
+
+// URA lot type and vehicle category codes mapped onto the LTA lot type codes
+var (
+	uraLotTypes = map[string]string{
+		"C": model.LotTypeCar,
+		"M": model.LotTypeMotorcycle,
+		"H": model.LotTypeHeavy,
+	}
+	uraVehCatLotTypes = map[string]string{
+		"Car":           model.LotTypeCar,
+		"Motorcycle":    model.LotTypeMotorcycle,
+		"Heavy Vehicle": model.LotTypeHeavy,
+	}
+)
+
+// errURAFailed is returned when URA answers with a status other than Success
+var errURAFailed = errors.New("URA request failed")
+
+// URASource is the URA Data Service feed of URA car parks.
+// Car_Park_Availability gives the available lots, Car_Park_Details the name, capacity and rates
+type URASource struct {
+	accessKey string
+	tokens    *TokenManager
+	avai      *model.URA_CarParkAvai_Resp
+	details   *model.URA_CarParkDetails_Resp
+	fetchedAt string
+}
+
+func NewURASource(accessKey string, tokens *TokenManager) *URASource {
+	return &URASource{accessKey: accessKey, tokens: tokens}
+}
+
+func (source *URASource) Name() string { return "URA Data Service" }
+
+func (source *URASource) ID() string { return URASourceID }
+
+func (source *URASource) Priority() int { return URASourcePriority }
+
+func (source *URASource) Fetch(ctx context.Context, client *http.Client) error {
+	if source.tokens.Token() == "" {
+		return fmt.Errorf("URA token not loaded yet")
+	}
+
+	var avai model.URA_CarParkAvai_Resp
+	if err := source.invoke(ctx, client, "Car_Park_Availability", &avai, func() (string, string) { return avai.Status, avai.Message }); err != nil {
+		return err
+	}
+
+	var details model.URA_CarParkDetails_Resp
+	if err := source.invoke(ctx, client, "Car_Park_Details", &details, func() (string, string) { return details.Status, details.Message }); err != nil {
+		return err
+	}
+
+	source.avai = &avai
+	source.details = &details
+	source.fetchedAt = time.Now().Format("2006-01-02T15:04:05")
+	log.Printf("Fetched Car Park Information from URA (%d availability, %d details records)", len(avai.Result), len(details.Result))
+	return nil
+}
+
+// invoke calls a URA service and checks the status of its response, which status returns once v is decoded.
+// An expired or invalid token may be reported either as a 401 or as a 200 whose status is not Success,
+// so on both the token is renewed and the call retried once
+func (source *URASource) invoke(ctx context.Context, client *http.Client, service string, v any, status func() (string, string)) error {
+	uraToken := source.tokens.Token()
+	err := source.invokeWithToken(ctx, client, service, uraToken, v, status)
+	if errors.Is(err, errUnauthorized) || errors.Is(err, errURAFailed) {
+		log.Printf("URA rejected %s, renewing the token: %v", service, err)
+		if err := source.tokens.Renew(uraToken); err != nil {
+			return fmt.Errorf("%s: fail to renew URA token: %v", service, err)
+		}
+		err = source.invokeWithToken(ctx, client, service, source.tokens.Token(), v, status)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %v", service, err)
+	}
+	return nil
+}
+
+func (source *URASource) invokeWithToken(ctx context.Context, client *http.Client, service, uraToken string, v any, status func() (string, string)) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?service=%s", URADataServiceURL, service), nil)
+	if err != nil {
+		return fmt.Errorf("fail to create request: %v", err)
+	}
+
+	req.Header.Add("AccessKey", source.accessKey)
+	req.Header.Add("Token", uraToken)
+	if err := fetchJSON(client, req, v); err != nil {
+		return err
+	}
+
+	if code, message := status(); code != "Success" {
+		return fmt.Errorf("%w: %s", errURAFailed, message)
+	}
+	return nil
+}
+
+func (source *URASource) Normalize() []*model.CarPark {
+	if source.avai == nil {
+		return nil
+	}
+
+	svy21_Converter := utils.NewSVY21()
+	carParks := make(map[string]*model.CarPark)
+	result := []*model.CarPark{}
+
+	getCarPark := func(carParkNo string, geometries []struct {
+		Coordinates string `json:"coordinates"`
+	}) *model.CarPark {
+		carPark, ok := carParks[carParkNo]
+		if !ok {
+			// URA does not report whether a car park is surface, multi-storey or basement, the type stays unknown
+			carPark = &model.CarPark{
+				CarParkID:  carParkNo,
+				LotDetails: make(map[string]*model.Lot),
+			}
+			carParks[carParkNo] = carPark
+			result = append(result, carPark)
+		}
+
+		if carPark.Latitude == 0 && carPark.Longitude == 0 && len(geometries) > 0 {
+			carPark.Latitude, carPark.Longitude = parseSVY21Coordinates(svy21_Converter, geometries[0].Coordinates)
+		}
+		return carPark
+	}
+
+	for _, info := range source.avai.Result {
+		lotType, ok := uraLotTypes[info.LotType]
+		if !ok {
+			continue
+		}
+
+		carPark := getCarPark(info.CarparkNo, info.Geometries)
+		carPark.LotDetails[lotType] = &model.Lot{
+			AvailableLots:  info.LotsAvailable,
+			UpdateDatetime: source.fetchedAt,
+		}
+	}
+
+	if source.details != nil {
+		for _, detail := range source.details.Result {
+			// Details list every URA car park, only enrich the ones that report availability
+			carPark, ok := carParks[detail.PPCode]
+			if !ok {
+				continue
+			}
+
+			if carPark.Address == "" {
+				carPark.Address = detail.PPName
+			}
+
+			lotType, ok := uraVehCatLotTypes[detail.VehCat]
+			if !ok {
+				continue
+			}
+
+			// every record is one charging band of the vehicle category
+			if carPark.Rates == nil {
+				carPark.Rates = make(map[string]*model.LotRates)
+			}
+			if _, ok := carPark.Rates[lotType]; !ok {
+				carPark.Rates[lotType] = &model.LotRates{Source: "URA"}
+			}
+			addURARateBands(carPark.Rates[lotType], detail.StartTime, detail.EndTime, [3][2]string{
+				{detail.WeekdayRate, detail.WeekdayMin},
+				{detail.SatdayRate, detail.SatdayMin},
+				{detail.SunPHRate, detail.SunPHMin},
+			})
+
+			if lot, ok := carPark.LotDetails[lotType]; ok && lot.TotalLots == "" && detail.ParkCapacity > 0 {
+				lot.TotalLots = strconv.Itoa(detail.ParkCapacity)
+			}
+		}
+	}
+
+	return result
+}
+
+// parseSVY21Coordinates converts the URA "easting,northing" string to latitude and longitude
+func parseSVY21Coordinates(converter *utils.SVY21, coordinates string) (float64, float64) {
+	eastNorth := strings.Split(coordinates, ",")
+	if len(eastNorth) != 2 {
+		return 0, 0
+	}
+
+	easting, errEast := strconv.ParseFloat(strings.TrimSpace(eastNorth[0]), 64)
+	northing, errNorth := strconv.ParseFloat(strings.TrimSpace(eastNorth[1]), 64)
+	if errEast != nil || errNorth != nil {
+		return 0, 0
+	}
+
+	return converter.ToLatLon(northing, easting)
+}
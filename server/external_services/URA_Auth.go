@@ -1,46 +1,84 @@
 package external_services
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/SC2006-Lab/MobileAppProject/model"
 	"github.com/SC2006-Lab/MobileAppProject/utils"
 )
 
-func URA_Init(token *string) {
+const (
+	uraTokenURL = "https://eservice.ura.gov.sg/uraDataService/insertNewToken/v1"
+	// URA tokens are only valid for a day and have to be requested again daily
+	uraTokenTTL          = 24 * time.Hour
+	uraTokenRefreshAhead = time.Hour
+)
+
+func NewURATokenManager(client *http.Client) *TokenManager {
+	return &TokenManager{
+		name: "URA",
+		fetch: func(ctx context.Context) (string, time.Time, error) {
+			return fetchURAToken(ctx, client)
+		},
+		renewAt: func(expiry time.Time) time.Time {
+			return expiry.Add(-uraTokenRefreshAhead)
+		},
+	}
+}
+
+// URA_Init gets the first token, unless one was restored, and starts its background renewal.
+// Nothing is started if getting the token fails
+func URA_Init(tokens *TokenManager) error {
+	if tokens.Token() != "" {
+		log.Println("Using restored URA Token")
+	} else {
+		log.Println("Getting URA Token")
+		if err := tokens.renew(); err != nil {
+			return fmt.Errorf("fail to get URA token: %v", err)
+		}
+		log.Println("URA Token retrieved successfully")
+	}
+
+	tokens.Start()
+	return nil
+}
+
+func fetchURAToken(ctx context.Context, client *http.Client) (string, time.Time, error) {
 	envConfig := utils.GetEnvConfig()
-	log.Println("Getting URA Token")
 	acessKey := envConfig.URA_ACCESS_KEY
 
-	client := &http.Client{}
-
-	req, err := http.NewRequest("GET", "https://eservice.ura.gov.sg/uraDataService/insertNewToken/v1", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", uraTokenURL, nil)
 	if err != nil {
-		log.Fatal("Fail to create request:", err)
+		return "", time.Time{}, fmt.Errorf("fail to create request: %v", err)
 	}
 
 	req.Header.Add("AccessKey", acessKey)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatal("Fail to send request:", err)
+		return "", time.Time{}, fmt.Errorf("fail to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatal("Fail to read response body:", err)
+		return "", time.Time{}, fmt.Errorf("fail to read response body: %v", err)
 	}
 
 	var URA_Resp model.URA_Resp
 	err = json.Unmarshal(body, &URA_Resp)
 	if err != nil {
-		log.Fatal("Fail to unmarshal JSON:", err)
+		return "", time.Time{}, fmt.Errorf("fail to unmarshal JSON: %v", err)
+	}
+	if URA_Resp.Status != "Success" || URA_Resp.Result == "" {
+		return "", time.Time{}, fmt.Errorf("URA token request failed: %s", URA_Resp.Message)
 	}
 
-	*token = URA_Resp.Result
-	log.Println("URA Token retrieved successfully")
+	return URA_Resp.Result, time.Now().Add(uraTokenTTL), nil
 }
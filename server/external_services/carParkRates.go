@@ -0,0 +1,125 @@
+package external_services
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+// HDB car parks in the central area charge the higher day rate
+// Ref: https://www.hdb.gov.sg/car-parks/shortterm-parking/short-term-parking-charges
+var hdbCentralCarParks = map[string]bool{
+	"ACB": true, "BBB": true, "BRB1": true, "CY": true, "DUXM": true, "HLM": true, "KAB": true, "KAM": true,
+	"KAS": true, "PRM": true, "SLS": true, "SR1": true, "SR2": true, "TPM": true, "UCS": true, "WCB": true,
+}
+
+const (
+	hdbCarRate          = 0.60
+	hdbCentralCarRate   = 1.20
+	hdbNightParkingCap  = 5.00
+	hdbMotorcycleRate   = 0.65
+	hdbHeavyVehicleRate = 1.20
+)
+
+// hdbRates builds the HDB short term parking charges of a car park
+func hdbRates(carParkID, freeParking string) map[string]*model.LotRates {
+	carBands := []model.RateBand{}
+	if hdbCentralCarParks[carParkID] {
+		carBands = append(carBands,
+			model.RateBand{DayType: model.DayTypeWeekday, StartMinute: 7 * 60, EndMinute: 17 * 60, Rate: hdbCentralCarRate, BlockMinutes: 30},
+			model.RateBand{DayType: model.DayTypeSaturday, StartMinute: 7 * 60, EndMinute: 17 * 60, Rate: hdbCentralCarRate, BlockMinutes: 30},
+		)
+	}
+	carBands = append(carBands,
+		// night parking from 10.30pm to 7am is capped
+		model.RateBand{DayType: model.DayTypeAll, StartMinute: 22*60 + 30, EndMinute: 7 * 60, Rate: hdbCarRate, BlockMinutes: 30, Cap: hdbNightParkingCap},
+		model.RateBand{DayType: model.DayTypeAll, StartMinute: 0, EndMinute: 24 * 60, Rate: hdbCarRate, BlockMinutes: 30},
+	)
+
+	if freeParking == "NO" {
+		freeParking = ""
+	}
+
+	return map[string]*model.LotRates{
+		model.LotTypeCar: {Source: "HDB", Bands: carBands, FreeParking: freeParking},
+		// motorcycles pay once per day and once per night
+		model.LotTypeMotorcycle: {Source: "HDB", FreeParking: freeParking, Bands: []model.RateBand{
+			{DayType: model.DayTypeAll, StartMinute: 7 * 60, EndMinute: 22*60 + 30, Rate: hdbMotorcycleRate, BlockMinutes: 15*60 + 30},
+			{DayType: model.DayTypeAll, StartMinute: 22*60 + 30, EndMinute: 7 * 60, Rate: hdbMotorcycleRate, BlockMinutes: 8*60 + 30},
+		}},
+		model.LotTypeHeavy: {Source: "HDB", Bands: []model.RateBand{
+			{DayType: model.DayTypeAll, StartMinute: 0, EndMinute: 24 * 60, Rate: hdbHeavyVehicleRate, BlockMinutes: 30},
+		}},
+	}
+}
+
+// addURARateBands adds the weekday, Saturday and Sunday/PH bands of one Car_Park_Details record
+func addURARateBands(rates *model.LotRates, startTime, endTime string, dayRates [3][2]string) {
+	start, okStart := parseURAClock(startTime)
+	end, okEnd := parseURAClock(endTime)
+	if !okStart || !okEnd {
+		return
+	}
+
+	for i, dayType := range []string{model.DayTypeWeekday, model.DayTypeSaturday, model.DayTypeSunday} {
+		rate, okRate := parseURARate(dayRates[i][0])
+		blockMinutes, okMinutes := parseURAMinutes(dayRates[i][1])
+		if !okRate || !okMinutes || rate == 0 {
+			continue
+		}
+
+		rates.Bands = append(rates.Bands, model.RateBand{
+			DayType:      dayType,
+			StartMinute:  start,
+			EndMinute:    end,
+			Rate:         rate,
+			BlockMinutes: blockMinutes,
+		})
+	}
+}
+
+// parseURAClock turns "08.30 AM" into minutes after midnight, "11.59 PM" is taken as the end of the day
+func parseURAClock(clock string) (int, bool) {
+	fields := strings.Fields(strings.ToUpper(clock))
+	if len(fields) != 2 {
+		return 0, false
+	}
+
+	hourMinute := strings.SplitN(fields[0], ".", 2)
+	if len(hourMinute) != 2 {
+		return 0, false
+	}
+	hour, errHour := strconv.Atoi(hourMinute[0])
+	minute, errMinute := strconv.Atoi(hourMinute[1])
+	if errHour != nil || errMinute != nil || hour < 1 || hour > 12 || minute > 59 {
+		return 0, false
+	}
+
+	hour %= 12
+	if fields[1] == "PM" {
+		hour += 12
+	}
+
+	minutes := hour*60 + minute
+	if minutes == 23*60+59 {
+		minutes = 24 * 60
+	}
+	return minutes, true
+}
+
+// parseURARate turns "$0.50" into 0.5
+func parseURARate(rate string) (float64, bool) {
+	value, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(rate), "$"), 64)
+	return value, err == nil && value >= 0
+}
+
+// parseURAMinutes turns "30 mins" into 30
+func parseURAMinutes(minutes string) (int, bool) {
+	fields := strings.Fields(minutes)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	value, err := strconv.Atoi(fields[0])
+	return value, err == nil && value > 0
+}
@@ -0,0 +1,222 @@
+package external_services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+// IDs of the built in sources, their readiness is tracked under these names
+const (
+	LTASourceID            = "lta"
+	URASourceID            = "ura"
+	DataGovAvaiSourceID    = "dataGovAvailability"
+	DataGovCarParkSourceID = "dataGovCarParks"
+)
+
+// Priorities of the built in sources, higher wins when two sources fill the same field
+const (
+	LTASourcePriority            = 300
+	URASourcePriority            = 250
+	DataGovAvaiSourcePriority    = 200
+	DataGovCarParkSourcePriority = 100
+)
+
+// errUnauthorized is returned by fetchJSON when the upstream rejects the token or key of the request
+var errUnauthorized = errors.New("unauthorized")
+
+// CarParkSource is one upstream feed of car park information.
+// New feeds (malls, private operators...) only need to implement this interface and be added to NewCarParkSources
+type CarParkSource interface {
+	// Name is used for logging
+	Name() string
+	// ID identifies the source across refreshes
+	ID() string
+	// Priority decides which source wins when several sources report the same field of a car park, higher wins
+	Priority() int
+	// Fetch calls the upstream and keeps the decoded response for Normalize
+	Fetch(ctx context.Context, client *http.Client) error
+	// Normalize converts the last fetched response into car parks
+	Normalize() []*model.CarPark
+}
+
+// NewCarParkSources returns a fresh set of the sources polled on every refresh
+func NewCarParkSources(envConfig *utils.EnvConfig, uraTokens *TokenManager) []CarParkSource {
+	return []CarParkSource{
+		NewLTASource(envConfig.LTA_ACCOUNT_KEY),
+		NewURASource(envConfig.URA_ACCESS_KEY, uraTokens),
+		NewDataGovAvaiSource(),
+		NewDataGovCarParkSource(),
+	}
+}
+
+// CarParkSourceIDs lists the IDs of the sources returned by NewCarParkSources
+func CarParkSourceIDs() []string {
+	return []string{LTASourceID, URASourceID, DataGovAvaiSourceID, DataGovCarParkSourceID}
+}
+
+// SourceCarParks is the normalized output of one source, kept until the source is fetched successfully again
+type SourceCarParks struct {
+	Priority int              `json:"priority"`
+	CarParks []*model.CarPark `json:"carParks"`
+}
+
+// MergeCarParks merges the normalized output of every source into one map.
+// The car parks of the sources are copied, never modified, so the same output can be merged again on the next refresh.
+//
+// Precedence rules:
+//   - sources are applied from the highest to the lowest priority
+//   - a field of a car park is only taken from a source if no higher priority source has filled it
+//   - latitude and longitude are treated as a single field
+//   - rates are taken per lot type
+//   - for a lot, AvailableLots and its UpdateDatetime are taken together, TotalLots separately
+//   - car parks without coordinates or without any lot are dropped, so reference only
+//     sources can enrich car parks but never introduce ones with no availability
+func MergeCarParks(sources []SourceCarParks) map[string]*model.CarPark {
+	carPark := mergeSources(sources)
+	CleanCarParkInfo(carPark)
+	return carPark
+}
+
+// MergeAvailability merges the sources like MergeCarParks but keeps every car park,
+// so the availability reported by some of the sources can be told apart from the merge of all of them
+func MergeAvailability(sources []SourceCarParks) map[string]*model.CarPark {
+	return mergeSources(sources)
+}
+
+func mergeSources(sources []SourceCarParks) map[string]*model.CarPark {
+	ordered := make([]SourceCarParks, len(sources))
+	copy(ordered, sources)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+
+	carPark := model.NewCarPark()
+	for _, source := range ordered {
+		for _, incoming := range source.CarParks {
+			existing, ok := carPark[incoming.CarParkID]
+			if !ok {
+				carPark[incoming.CarParkID] = cloneCarPark(incoming)
+				continue
+			}
+			mergeCarPark(existing, incoming)
+		}
+	}
+	return carPark
+}
+
+// cloneCarPark copies the parts of a car park that merging modifies, rates are never modified and stay shared
+func cloneCarPark(carPark *model.CarPark) *model.CarPark {
+	clone := *carPark
+	if carPark.Rates != nil {
+		clone.Rates = make(map[string]*model.LotRates, len(carPark.Rates))
+		for lotType, rates := range carPark.Rates {
+			clone.Rates[lotType] = rates
+		}
+	}
+	clone.LotDetails = make(map[string]*model.Lot, len(carPark.LotDetails))
+	for lotType, lot := range carPark.LotDetails {
+		lotCopy := *lot
+		clone.LotDetails[lotType] = &lotCopy
+	}
+	return &clone
+}
+
+func mergeCarPark(existing, incoming *model.CarPark) {
+	if existing.Address == "" {
+		existing.Address = incoming.Address
+	}
+	if existing.CarParkType == "" {
+		existing.CarParkType = incoming.CarParkType
+	}
+	if existing.Latitude == 0 && existing.Longitude == 0 {
+		existing.Latitude = incoming.Latitude
+		existing.Longitude = incoming.Longitude
+	}
+	if existing.GantryHeight == nil {
+		existing.GantryHeight = incoming.GantryHeight
+	}
+	if existing.TypeOfParkingSystem == "" {
+		existing.TypeOfParkingSystem = incoming.TypeOfParkingSystem
+	}
+	if existing.ShortTermParking == "" {
+		existing.ShortTermParking = incoming.ShortTermParking
+	}
+	if existing.FreeParking == "" {
+		existing.FreeParking = incoming.FreeParking
+	}
+	if existing.NightParking == nil {
+		existing.NightParking = incoming.NightParking
+	}
+	if existing.CarParkDecks == nil {
+		existing.CarParkDecks = incoming.CarParkDecks
+	}
+	if existing.CarParkBasement == nil {
+		existing.CarParkBasement = incoming.CarParkBasement
+	}
+
+	for lotType, incomingRates := range incoming.Rates {
+		if existing.Rates == nil {
+			existing.Rates = make(map[string]*model.LotRates)
+		}
+		if _, ok := existing.Rates[lotType]; !ok {
+			existing.Rates[lotType] = incomingRates
+		}
+	}
+
+	if existing.LotDetails == nil {
+		existing.LotDetails = make(map[string]*model.Lot)
+	}
+	for lotType, incomingLot := range incoming.LotDetails {
+		existingLot, ok := existing.LotDetails[lotType]
+		if !ok {
+			lotCopy := *incomingLot
+			existing.LotDetails[lotType] = &lotCopy
+			continue
+		}
+		if existingLot.TotalLots == "" {
+			existingLot.TotalLots = incomingLot.TotalLots
+		}
+		if existingLot.AvailableLots == "" {
+			existingLot.AvailableLots = incomingLot.AvailableLots
+			existingLot.UpdateDatetime = incomingLot.UpdateDatetime
+		}
+	}
+}
+
+// fetchJSON sends the request and decodes a 200 response into v.
+// The call is bounded by UPSTREAM_CALL_TIMEOUT on top of whatever deadline the request context has
+func fetchJSON(client *http.Client, req *http.Request, v any) error {
+	ctx, cancel := context.WithTimeout(req.Context(), utils.GetEnvConfig().UPSTREAM_CALL_TIMEOUT)
+	defer cancel()
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("fail to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("fail to read response body: %v", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("fail to unmarshal JSON: %v", err)
+	}
+	return nil
+}
@@ -1,17 +1,54 @@
 package external_services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	_ "log"
+	"log"
 	"net/http"
 	"net/url"
 
 	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
 )
 
-func ComputeRoute(originLat, originLng, destLat, destLng float64, oneMapToken string, client *http.Client) (*model.RouteInfo, error) {
+const (
+	// roads are rarely straight, used to turn the straight-line distance into a drive time
+	estimatedDetourFactor = 1.3
+	estimatedDrivingSpeed = 30.0 // km/h, average urban speed including junctions
+)
+
+// EstimateRoute is the fallback when OneMap cannot route, the straight-line distance with a rough drive time and no polyline
+func EstimateRoute(originLat, originLng, destLat, destLng float64) *model.RouteInfo {
+	distanceKm := utils.CalculateDistance(originLat, originLng, destLat, destLng)
+	return &model.RouteInfo{
+		Distance: distanceKm * 1000,
+		Duration: distanceKm * estimatedDetourFactor / estimatedDrivingSpeed * 3600,
+	}
+}
+
+// ComputeRoute asks OneMap for the driving route, renewing the token and retrying once if OneMap rejects it
+func ComputeRoute(ctx context.Context, originLat, originLng, destLat, destLng float64, tokens *TokenManager, client *http.Client) (*model.RouteInfo, error) {
+	oneMapToken := tokens.Token()
+	routeInfo, err := computeRoute(ctx, originLat, originLng, destLat, destLng, oneMapToken, client)
+	if !errors.Is(err, ErrOneMapUnauthorized) {
+		return routeInfo, err
+	}
+
+	log.Println("OneMap token rejected, renewing it")
+	if err := tokens.Renew(oneMapToken); err != nil {
+		return nil, fmt.Errorf("failed to renew OneMap token: %v", err)
+	}
+	return computeRoute(ctx, originLat, originLng, destLat, destLng, tokens.Token(), client)
+}
+
+// computeRoute makes a single OneMap call, bounded by ROUTE_CALL_TIMEOUT
+func computeRoute(ctx context.Context, originLat, originLng, destLat, destLng float64, oneMapToken string, client *http.Client) (*model.RouteInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, utils.GetEnvConfig().ROUTE_CALL_TIMEOUT)
+	defer cancel()
+
 	baseURL := "https://www.onemap.gov.sg/api/public/routingsvc/route"
 	uObj, err := url.Parse(baseURL)
 	if err != nil {
@@ -24,7 +61,7 @@ func ComputeRoute(originLat, originLng, destLat, destLng float64, oneMapToken st
 	query.Add("routeType", "drive")
 	uObj.RawQuery = query.Encode()
 
-	req, err := http.NewRequest("GET", uObj.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", uObj.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -37,6 +74,13 @@ func ComputeRoute(originLat, originLng, destLat, destLng float64, oneMapToken st
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrOneMapUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %v", err)
@@ -48,6 +92,14 @@ func ComputeRoute(originLat, originLng, destLat, destLng float64, oneMapToken st
 		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
 	}
 
+	// an error or a missing summary would otherwise pass as a zero length route and be cached as such
+	if OneMap_Resp.Error != "" {
+		return nil, fmt.Errorf("OneMap returned an error: %s", OneMap_Resp.Error)
+	}
+	if OneMap_Resp.RouteSummary == nil {
+		return nil, fmt.Errorf("OneMap returned no route")
+	}
+
 	routeInfo := &model.RouteInfo{
 		Distance: OneMap_Resp.RouteSummary.TotalDistance,
 		Duration: OneMap_Resp.RouteSummary.TotalTime,
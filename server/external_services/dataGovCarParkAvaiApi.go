@@ -0,0 +1,87 @@
+package external_services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+const DataGovCarParkAvaiURL = "https://api.data.gov.sg/v1/transport/carpark-availability"
+
+// DataGovAvaiSource is the data.gov.sg HDB car park availability feed.
+// It has no location, only lot counts keyed by HDB car park number
+type DataGovAvaiSource struct {
+	resp *model.DataGov_API_CarParkAvai_Resp
+}
+
+func NewDataGovAvaiSource() *DataGovAvaiSource {
+	return &DataGovAvaiSource{}
+}
+
+func (source *DataGovAvaiSource) Name() string { return "DataGov (Car Park Availability)" }
+
+func (source *DataGovAvaiSource) ID() string { return DataGovAvaiSourceID }
+
+func (source *DataGovAvaiSource) Priority() int { return DataGovAvaiSourcePriority }
+
+func (source *DataGovAvaiSource) Fetch(ctx context.Context, client *http.Client) error {
+	currentTime := time.Now().Format("2006-01-02T15:04:05")
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?date_time=%s", DataGovCarParkAvaiURL, currentTime), nil)
+	if err != nil {
+		return fmt.Errorf("fail to create request: %v", err)
+	}
+
+	var resp model.DataGov_API_CarParkAvai_Resp
+	if err := fetchJSON(client, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Items) == 0 {
+		return fmt.Errorf("no car park availability items returned from DataGov")
+	}
+
+	source.resp = &resp
+	log.Println("Fetched Car Park Information from DataGov (Car Park Availability)")
+	return nil
+}
+
+func (source *DataGovAvaiSource) Normalize() []*model.CarPark {
+	if source.resp == nil {
+		return nil
+	}
+
+	carParkData := source.resp.Items[0].CarparkData
+	result := make([]*model.CarPark, 0, len(carParkData))
+
+	for _, carpark_data := range carParkData {
+		// skip car parks whose sensors stopped reporting
+		if len(carpark_data.UpdateDatetime) < 4 {
+			continue
+		}
+		dataYear, _ := strconv.Atoi(carpark_data.UpdateDatetime[:4])
+		if dataYear != time.Now().Year() {
+			continue
+		}
+
+		carPark := &model.CarPark{
+			CarParkID:  carpark_data.CarparkNumber,
+			LotDetails: make(map[string]*model.Lot),
+		}
+
+		for _, carpark_info := range carpark_data.CarparkInfo {
+			carPark.LotDetails[carpark_info.LotType] = &model.Lot{
+				TotalLots:      carpark_info.TotalLots,
+				AvailableLots:  carpark_info.LotsAvailable,
+				UpdateDatetime: carpark_data.UpdateDatetime,
+			}
+		}
+
+		result = append(result, carPark)
+	}
+
+	return result
+}
@@ -0,0 +1,102 @@
+package external_services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+const DataGovCarParkInfoURL = "https://data.gov.sg/api/action/datastore_search?resource_id=139a3035-e624-4f56-b63f-89ae28d4ae4c&limit=3000&q="
+
+// DataGovCarParkSource is the data.gov.sg HDB car park information dataset.
+// It is reference data only (address, type, SVY21 coordinates) without any lot counts
+type DataGovCarParkSource struct {
+	resp *model.DataGov_Api_CarParkInfo_Resp
+}
+
+func NewDataGovCarParkSource() *DataGovCarParkSource {
+	return &DataGovCarParkSource{}
+}
+
+func (source *DataGovCarParkSource) Name() string { return "DataGov (Car Park Info)" }
+
+func (source *DataGovCarParkSource) ID() string { return DataGovCarParkSourceID }
+
+func (source *DataGovCarParkSource) Priority() int { return DataGovCarParkSourcePriority }
+
+func (source *DataGovCarParkSource) Fetch(ctx context.Context, client *http.Client) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", DataGovCarParkInfoURL, nil)
+	if err != nil {
+		return fmt.Errorf("fail to create request: %v", err)
+	}
+
+	var resp model.DataGov_Api_CarParkInfo_Resp
+	if err := fetchJSON(client, req, &resp); err != nil {
+		return err
+	}
+
+	source.resp = &resp
+	log.Println("Fetched Car Park Information from DataGov (Car Park Info)")
+	return nil
+}
+
+func (source *DataGovCarParkSource) Normalize() []*model.CarPark {
+	if source.resp == nil {
+		return nil
+	}
+
+	svy21_Converter := utils.NewSVY21()
+	records := source.resp.Result.Records
+	result := make([]*model.CarPark, 0, len(records))
+
+	for _, carpark_info := range records {
+		carPark := &model.CarPark{
+			CarParkID:           carpark_info.CarparkNumber,
+			Address:             carpark_info.Address,
+			CarParkType:         carpark_info.CarParkType,
+			TypeOfParkingSystem: carpark_info.TypeOfParkingSystem,
+			ShortTermParking:    carpark_info.ShortTermParking,
+			FreeParking:         carpark_info.FreeParking,
+			Rates:               hdbRates(carpark_info.CarparkNumber, carpark_info.FreeParking),
+		}
+
+		if gantryHeight, err := strconv.ParseFloat(carpark_info.GantryHeight, 64); err == nil {
+			carPark.GantryHeight = &gantryHeight
+		}
+		if decks, err := strconv.Atoi(carpark_info.CarParkDecks); err == nil {
+			carPark.CarParkDecks = &decks
+		}
+		carPark.NightParking = parseYesNo(carpark_info.NightParking)
+		carPark.CarParkBasement = parseYesNo(carpark_info.CarParkBasement)
+
+		xCoord, _ := strconv.ParseFloat(carpark_info.XCoord, 64)
+		yCoord, _ := strconv.ParseFloat(carpark_info.YCoord, 64)
+		if xCoord != 0 && yCoord != 0 {
+			carPark.Latitude, carPark.Longitude = svy21_Converter.ToLatLon(yCoord, xCoord)
+		}
+
+		result = append(result, carPark)
+	}
+
+	return result
+}
+
+// parseYesNo reads the "YES"/"NO" and "Y"/"N" flags of the HDB dataset, nil if it is neither
+func parseYesNo(value string) *bool {
+	var flag bool
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "YES", "Y":
+		flag = true
+	case "NO", "N":
+		flag = false
+	default:
+		return nil
+	}
+	return &flag
+}
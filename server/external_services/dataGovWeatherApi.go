@@ -1,32 +1,29 @@
 package external_services
 
 import (
-	"encoding/json"
-	_ "fmt"
-	"io"
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/SC2006-Lab/MobileAppProject/model"
 )
 
-func InitWeatherInformation(areaData map[string]*model.WeatherAreaInfo) {
+// FetchWeatherInformation gets the latest two-hour forecast of every area into a brand new map
+func FetchWeatherInformation(ctx context.Context, client *http.Client) (map[string]*model.WeatherAreaInfo, error) {
 	log.Println("Fetching Weather Information from DataGov")
-	resp, err := http.Get("https://api-open.data.gov.sg/v2/real-time/api/two-hr-forecast")
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api-open.data.gov.sg/v2/real-time/api/two-hr-forecast", nil)
 	if err != nil {
-		log.Fatalf("Fail to fetch URL: %v", err)
+		return nil, fmt.Errorf("fail to create request: %v", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatalf("Fail to read response body: %v", err)
+	var response model.DataGov_Api_Weather_Resp
+	if err := fetchJSON(client, req, &response); err != nil {
+		return nil, err
 	}
 	log.Println("Fetched Weather Information from DataGov")
-
-	var response model.DataGov_Api_Weather_Resp
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		log.Fatalf("Fail to unmarshal JSON: %v", err)
+	if len(response.Data.Items) == 0 {
+		return nil, fmt.Errorf("no forecast in response: %s", response.ErrorMsg)
 	}
 
 	// temp map to store location information
@@ -38,22 +35,23 @@ func InitWeatherInformation(areaData map[string]*model.WeatherAreaInfo) {
 
 	// map to store the data for easy accessing the data of the area
 	// area -> weather information & area information
-	for _, forecast := range response.Data.Items[0].Forecasts {
+	item := response.Data.Items[0]
+	areaData := model.NewWeatherAreaInfo()
+	for _, forecast := range item.Forecasts {
 		location, exists := locationData[forecast.Area]
 		if exists {
 			areaData[forecast.Area] = &model.WeatherAreaInfo{
-				Name:      forecast.Area,
-				Latitude:  location.Latitude,
-				Longitude: location.Longitude,
-				Weather:   forecast.Forecast,
+				Name:            forecast.Area,
+				Latitude:        location.Latitude,
+				Longitude:       location.Longitude,
+				Weather:         forecast.Forecast,
+				ValidFrom:       item.ValidPeriod.Start,
+				ValidTo:         item.ValidPeriod.End,
+				UpdateTimestamp: item.UpdateTimestamp,
 			}
 		}
 	}
 	log.Println("Processed Weather Information")
 
-	defer func() {
-		log.Println("Closing response body for Weather Information")
-		resp.Body.Close()
-	}()
-
+	return areaData, nil
 }
@@ -0,0 +1,158 @@
+package external_services
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+const (
+	// ~33m in latitude, close enough that the route barely differs
+	routeCachePrecision  = 0.0003
+	routeCacheMaxEntries = 20000
+)
+
+// routeKey is the origin / destination snapped to the cache grid
+type routeKey struct {
+	originLat, originLng int64
+	destLat, destLng     int64
+}
+
+func newRouteKey(originLat, originLng, destLat, destLng float64) routeKey {
+	snap := func(coordinate float64) int64 {
+		return int64(math.Round(coordinate / routeCachePrecision))
+	}
+	return routeKey{snap(originLat), snap(originLng), snap(destLat), snap(destLng)}
+}
+
+type routeEntry struct {
+	route     *model.RouteInfo
+	expiresAt time.Time
+}
+
+// routeCall is a OneMap request in flight, later callers for the same key wait on done
+type routeCall struct {
+	done  chan struct{}
+	route *model.RouteInfo
+	err   error
+}
+
+type RouteCacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Shared  uint64 `json:"shared"` // misses that waited on an identical request in flight
+	Entries int    `json:"entries"`
+}
+
+// RouteCache sits in front of ComputeRoute.
+// Routes are cached for ttl by rounded origin and destination and identical concurrent requests share one OneMap call.
+// Failed requests are never cached
+type RouteCache struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	entries  map[routeKey]routeEntry
+	inflight map[routeKey]*routeCall
+
+	hits, misses, shared atomic.Uint64
+}
+
+func NewRouteCache(ttl time.Duration) *RouteCache {
+	return &RouteCache{
+		ttl:      ttl,
+		entries:  make(map[routeKey]routeEntry),
+		inflight: make(map[routeKey]*routeCall),
+	}
+}
+
+// ComputeRoute returns the cached route if there is one, otherwise asks OneMap through ComputeRoute.
+// Every caller of a request in flight, the one that started it included, stops waiting when its own ctx is done,
+// the request itself carries on until ROUTE_CALL_TIMEOUT and its route is cached for the others
+func (cache *RouteCache) ComputeRoute(ctx context.Context, originLat, originLng, destLat, destLng float64, tokens *TokenManager, client *http.Client) (*model.RouteInfo, error) {
+	key := newRouteKey(originLat, originLng, destLat, destLng)
+	now := time.Now()
+
+	cache.mu.Lock()
+	if entry, ok := cache.entries[key]; ok && now.Before(entry.expiresAt) {
+		cache.mu.Unlock()
+		cache.hits.Add(1)
+		return entry.route, nil
+	}
+	cache.misses.Add(1)
+
+	call, ok := cache.inflight[key]
+	if ok {
+		cache.shared.Add(1)
+	} else {
+		call = &routeCall{done: make(chan struct{})}
+		cache.inflight[key] = call
+		// the call is shared, so it must not be cancelled when the request that started it goes away
+		callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), utils.GetEnvConfig().ROUTE_CALL_TIMEOUT)
+		go func() {
+			defer cancel()
+			cache.run(callCtx, key, call, originLat, originLng, destLat, destLng, tokens, client)
+		}()
+	}
+	cache.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.route, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run makes the OneMap call shared by every caller waiting on key, and caches its route
+func (cache *RouteCache) run(ctx context.Context, key routeKey, call *routeCall, originLat, originLng, destLat, destLng float64,
+	tokens *TokenManager, client *http.Client) {
+	call.route, call.err = ComputeRoute(ctx, originLat, originLng, destLat, destLng, tokens, client)
+
+	cache.mu.Lock()
+	delete(cache.inflight, key)
+	if call.err == nil && cache.ttl > 0 {
+		if len(cache.entries) >= routeCacheMaxEntries {
+			cache.evictLocked(time.Now())
+		}
+		cache.entries[key] = routeEntry{route: call.route, expiresAt: time.Now().Add(cache.ttl)}
+	}
+	cache.mu.Unlock()
+	close(call.done)
+}
+
+// evictLocked drops the expired routes, and if that is not enough an arbitrary quarter of the cache
+func (cache *RouteCache) evictLocked(now time.Time) {
+	for key, entry := range cache.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(cache.entries, key)
+		}
+	}
+
+	excess := len(cache.entries) - routeCacheMaxEntries*3/4
+	for key := range cache.entries {
+		if excess <= 0 {
+			break
+		}
+		delete(cache.entries, key)
+		excess--
+	}
+}
+
+func (cache *RouteCache) Stats() RouteCacheStats {
+	cache.mu.Lock()
+	entries := len(cache.entries)
+	cache.mu.Unlock()
+
+	return RouteCacheStats{
+		Hits:    cache.hits.Load(),
+		Misses:  cache.misses.Load(),
+		Shared:  cache.shared.Load(),
+		Entries: entries,
+	}
+}
@@ -0,0 +1,134 @@
+package external_services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+const tokenRetryDelay = time.Minute
+
+// TokenManager owns an upstream access token.
+// Workers read it with Token(), the background loop renews it when renewAt says so
+// and Renew can be called when the upstream rejects the token before that
+type TokenManager struct {
+	name    string
+	fetch   func(ctx context.Context) (string, time.Time, error)
+	renewAt func(expiry time.Time) time.Time
+
+	mu     sync.RWMutex
+	token  string
+	expiry time.Time
+
+	renewMu sync.Mutex // only one renewal talks to the upstream at a time
+
+	loopMu sync.Mutex
+	stop   chan struct{} // nil while the background renewal is not running
+}
+
+// Token returns the current access token
+func (tokens *TokenManager) Token() string {
+	tokens.mu.RLock()
+	defer tokens.mu.RUnlock()
+	return tokens.token
+}
+
+// Expiry returns when the current token stops being valid
+func (tokens *TokenManager) Expiry() time.Time {
+	tokens.mu.RLock()
+	defer tokens.mu.RUnlock()
+	return tokens.expiry
+}
+
+// Restore reuses a token saved by a previous run, it is refused when it is already due for renewal
+func (tokens *TokenManager) Restore(token string, expiry time.Time) bool {
+	if token == "" || !time.Now().Before(tokens.renewAt(expiry)) {
+		return false
+	}
+
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+	tokens.token = token
+	tokens.expiry = expiry
+	return true
+}
+
+// Renew fetches a new token after the upstream rejected staleToken.
+// If another worker already renewed it in the meantime nothing is fetched
+func (tokens *TokenManager) Renew(staleToken string) error {
+	tokens.renewMu.Lock()
+	defer tokens.renewMu.Unlock()
+
+	if tokens.Token() != staleToken {
+		return nil
+	}
+	return tokens.renewLocked()
+}
+
+func (tokens *TokenManager) renew() error {
+	tokens.renewMu.Lock()
+	defer tokens.renewMu.Unlock()
+	return tokens.renewLocked()
+}
+
+// renewLocked is not tied to the context of whoever triggered it, the new token is shared by every caller
+func (tokens *TokenManager) renewLocked() error {
+	ctx, cancel := context.WithTimeout(context.Background(), utils.GetEnvConfig().UPSTREAM_CALL_TIMEOUT)
+	defer cancel()
+
+	token, expiry, err := tokens.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	tokens.mu.Lock()
+	tokens.token = token
+	tokens.expiry = expiry
+	tokens.mu.Unlock()
+
+	log.Printf("%s Token renewed, expires at %s", tokens.name, expiry.Format(time.RFC3339))
+	return nil
+}
+
+// Start renews the token in the background ahead of its expiry, it does nothing if the renewal is already running
+func (tokens *TokenManager) Start() {
+	tokens.loopMu.Lock()
+	defer tokens.loopMu.Unlock()
+	if tokens.stop != nil {
+		return
+	}
+
+	// the loop only ever reads its own channel, Stop may reset the field at any time
+	stop := make(chan struct{})
+	tokens.stop = stop
+
+	go func() {
+		for {
+			wait := time.Until(tokens.renewAt(tokens.Expiry()))
+			if wait < tokenRetryDelay {
+				wait = tokenRetryDelay
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(wait):
+				if err := tokens.renew(); err != nil {
+					log.Printf("Error renewing %s Token, retrying in %s: %v", tokens.name, tokenRetryDelay, err)
+				}
+			}
+		}
+	}()
+}
+
+func (tokens *TokenManager) Stop() {
+	tokens.loopMu.Lock()
+	defer tokens.loopMu.Unlock()
+	if tokens.stop != nil {
+		close(tokens.stop)
+		tokens.stop = nil
+	}
+}
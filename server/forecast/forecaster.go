@@ -0,0 +1,287 @@
+package forecast
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/history"
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+const (
+	slotMinutes  = 15
+	slotsPerDay  = 24 * 60 / slotMinutes
+	slotsPerWeek = 7 * slotsPerDay
+
+	// the seasonal mean behaves like a moving average over this many weeks once it is full
+	maxSlotWeeks = 8
+	// a slot needs samples from this many different weeks before it is trusted
+	minSlotWeeks = 2
+
+	// Unix time of the first Sunday midnight in Singapore before the epoch, weeks are counted from there
+	weekOrigin = -(4*24 + 8) * 60 * 60
+
+	recentWindow = 30 * time.Minute
+	maxRecent    = 12
+	// how fast the current deviation from the baseline and the recent trend fade out
+	anomalyDecay = 60 * time.Minute
+	trendDecay   = 15 * time.Minute
+)
+
+type seriesKey struct {
+	carParkID string
+	lotType   string
+}
+
+// slot is the profile of one time of the week.
+// The samples of a week are averaged on their own first, so every week weighs the same whatever the refresh interval
+type slot struct {
+	mean        float32 // average of the completed weeks
+	weekMean    float32 // average of the samples of week
+	week        uint16
+	weeks       uint8 // completed weeks in mean, up to maxSlotWeeks
+	weekSamples uint8
+}
+
+// add adds a sample of the given week, weeks must come in order
+func (s *slot) add(week uint16, available float32) {
+	if s.weekSamples > 0 && week != s.week {
+		s.closeWeek()
+	}
+	s.week = week
+	if s.weekSamples < math.MaxUint8 {
+		s.weekSamples++
+	}
+	s.weekMean += (available - s.weekMean) / float32(s.weekSamples)
+}
+
+// closeWeek folds the average of the current week into the moving average of the weeks
+func (s *slot) closeWeek() {
+	if s.weeks < maxSlotWeeks {
+		s.weeks++
+	}
+	s.mean += (s.weekMean - s.mean) / float32(s.weeks)
+	s.weekMean, s.weekSamples = 0, 0
+}
+
+// baseline returns the usual level of the slot and from how many weeks it was learnt
+func (s *slot) baseline() (float64, int) {
+	switch {
+	case s.weekSamples == 0:
+		return float64(s.mean), int(s.weeks)
+	case s.weeks == 0:
+		return float64(s.weekMean), 1
+	}
+	weeks := float64(s.weeks)
+	return (float64(s.mean)*weeks + float64(s.weekMean)) / (weeks + 1), int(s.weeks) + 1
+}
+
+// absorb merges in a slot learnt from older samples, none of its weeks comes after the week of s
+func (s *slot) absorb(older slot) {
+	if s.weeks == 0 && s.weekSamples == 0 {
+		*s = older
+		return
+	}
+
+	if older.weekSamples > 0 {
+		if s.weekSamples > 0 && older.week == s.week {
+			samples := float32(older.weekSamples) + float32(s.weekSamples)
+			s.weekMean = (older.weekMean*float32(older.weekSamples) + s.weekMean*float32(s.weekSamples)) / samples
+			s.weekSamples = uint8(min(samples, math.MaxUint8))
+		} else {
+			older.closeWeek()
+		}
+	}
+
+	if older.weeks > 0 {
+		weeks := float32(older.weeks) + float32(s.weeks)
+		s.mean = (older.mean*float32(older.weeks) + s.mean*float32(s.weeks)) / weeks
+		s.weeks = uint8(min(weeks, maxSlotWeeks))
+	}
+}
+
+type recentSample struct {
+	at        time.Time
+	available float64
+}
+
+// series is the day-of-week/time-of-day profile of one lot type of a car park plus its latest samples
+type series struct {
+	seasonal  [slotsPerWeek]slot
+	recent    []recentSample
+	totalLots int
+}
+
+// Forecaster predicts the available lots of a car park at a future time.
+// The prediction is a seasonal weekly baseline, corrected by how far the car park currently is from it
+// and by the recent trend, both fading out the further ahead the prediction is
+type Forecaster struct {
+	mu        sync.RWMutex
+	series    map[seriesKey]*series
+	liveSince time.Time // first live snapshot, zero until then
+}
+
+func NewForecaster() *Forecaster {
+	return &Forecaster{series: make(map[seriesKey]*series)}
+}
+
+func slotOf(t time.Time) int {
+	local := t.In(model.SingaporeTime)
+	return int(local.Weekday())*slotsPerDay + (local.Hour()*60+local.Minute())/slotMinutes
+}
+
+// weekOf numbers the weeks, starting on Sunday midnight like the slots
+func weekOf(t time.Time) uint16 {
+	return uint16((t.Unix() - weekOrigin) / (7 * 24 * 60 * 60))
+}
+
+// Observe adds one sample, the samples of a car park have to come in time order
+func (forecaster *Forecaster) Observe(carParkID, lotType string, at time.Time, available, total int) {
+	forecaster.mu.Lock()
+	defer forecaster.mu.Unlock()
+
+	key := seriesKey{carParkID, lotType}
+	s, ok := forecaster.series[key]
+	if !ok {
+		s = &series{}
+		forecaster.series[key] = s
+	}
+
+	s.seasonal[slotOf(at)].add(weekOf(at), float32(available))
+
+	if len(s.recent) == 0 || at.After(s.recent[len(s.recent)-1].at) {
+		s.recent = append(s.recent, recentSample{at: at, available: float64(available)})
+		for len(s.recent) > maxRecent || (len(s.recent) > 1 && at.Sub(s.recent[0].at) > recentWindow) {
+			s.recent = s.recent[1:]
+		}
+	}
+
+	if total > 0 {
+		s.totalLots = total
+	}
+}
+
+// ObserveSnapshot adds the availability of every car park of a live refresh
+func (forecaster *Forecaster) ObserveSnapshot(carParks map[string]*model.CarPark, at time.Time) {
+	forecaster.mu.Lock()
+	if forecaster.liveSince.IsZero() {
+		forecaster.liveSince = at
+	}
+	forecaster.mu.Unlock()
+
+	for carParkID, carPark := range carParks {
+		for lotType, lot := range carPark.LotDetails {
+			available, err := strconv.Atoi(lot.AvailableLots)
+			if err != nil {
+				continue
+			}
+			total, _ := strconv.Atoi(lot.TotalLots)
+			forecaster.Observe(carParkID, lotType, at, available, total)
+		}
+	}
+}
+
+// Predict returns the expected available lots at arrival, false when the car park was never observed
+func (forecaster *Forecaster) Predict(carParkID, lotType string, arrival time.Time) (int, bool) {
+	forecaster.mu.RLock()
+	defer forecaster.mu.RUnlock()
+
+	s, ok := forecaster.series[seriesKey{carParkID, lotType}]
+	if !ok || len(s.recent) == 0 {
+		return 0, false
+	}
+
+	latest := s.recent[len(s.recent)-1]
+	ahead := arrival.Sub(latest.at)
+	if ahead < 0 {
+		ahead = 0
+	}
+
+	// recent trend in lots per minute, damped so it only matters for the next few minutes
+	trend := slope(s.recent)
+	trendMinutes := trendDecay.Minutes() * (1 - math.Exp(-ahead.Minutes()/trendDecay.Minutes()))
+	predicted := latest.available + trend*trendMinutes
+
+	baselineNow, weeksNow := s.seasonal[slotOf(latest.at)].baseline()
+	baselineArrival, weeksArrival := s.seasonal[slotOf(arrival)].baseline()
+	if weeksNow >= minSlotWeeks && weeksArrival >= minSlotWeeks {
+		// keep the deviation from the usual level, but let it fade towards the usual level at arrival
+		anomaly := predicted - baselineNow
+		predicted = baselineArrival + anomaly*math.Exp(-ahead.Minutes()/anomalyDecay.Minutes())
+	}
+
+	predicted = math.Max(predicted, 0)
+	if s.totalLots > 0 {
+		predicted = math.Min(predicted, float64(s.totalLots))
+	}
+	return int(math.Round(predicted)), true
+}
+
+// slope is the least squares slope of the samples in lots per minute
+func slope(samples []recentSample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	origin := samples[0].at
+	var sumX, sumY, sumXY, sumXX float64
+	for _, sample := range samples {
+		x := sample.at.Sub(origin).Minutes()
+		sumX += x
+		sumY += sample.available
+		sumXY += x * sample.available
+		sumXX += x * x
+	}
+
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// LoadHistory feeds the stored availability history since the given time into the seasonal profiles.
+// It runs alongside the live refreshes, so the history is learnt on the side and merged in at the end,
+// and the samples the live refreshes already observed are skipped
+func (forecaster *Forecaster) LoadHistory(store *history.Store, since time.Time) error {
+	loaded := NewForecaster()
+	err := store.Scan(since, func(carParkID string, sample model.AvailabilitySample) {
+		if liveSince := forecaster.firstLive(); !liveSince.IsZero() && !sample.Time.Before(liveSince) {
+			return
+		}
+		loaded.Observe(carParkID, sample.LotType, sample.Time, sample.AvailableLots, sample.TotalLots)
+	})
+
+	forecaster.merge(loaded)
+	return err
+}
+
+func (forecaster *Forecaster) firstLive() time.Time {
+	forecaster.mu.RLock()
+	defer forecaster.mu.RUnlock()
+	return forecaster.liveSince
+}
+
+// merge adds the profiles learnt from older samples to the live ones
+func (forecaster *Forecaster) merge(older *Forecaster) {
+	forecaster.mu.Lock()
+	defer forecaster.mu.Unlock()
+
+	for key, olderSeries := range older.series {
+		s, ok := forecaster.series[key]
+		if !ok {
+			forecaster.series[key] = olderSeries
+			continue
+		}
+
+		for i := range s.seasonal {
+			s.seasonal[i].absorb(olderSeries.seasonal[i])
+		}
+		if s.totalLots == 0 {
+			s.totalLots = olderSeries.totalLots
+		}
+	}
+}
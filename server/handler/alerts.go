@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/alerts"
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAlertTTL = 2 * time.Hour
+	maxAlertTTL     = 7 * 24 * time.Hour
+	maxAlertLots    = 10000
+	// resolving the host of a webhook target
+	webhookLookupTimeout = 5 * time.Second
+)
+
+var expoPushTokenRegex = regexp.MustCompile(`^Expo(nent)?PushToken\[[^\]]+\]$`)
+
+// AlertRequest is the body of POST /api/alerts, either CarParkID or a location with a radius is watched
+type AlertRequest struct {
+	CarParkID    string  `json:"carParkID"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	RadiusMeters float64 `json:"radiusMeters"`
+	LotType      string  `json:"lotType"`   // defaults to C
+	Condition    string  `json:"condition"` // above or below
+	Threshold    int     `json:"threshold"`
+	Channel      string  `json:"channel"`   // webhook or expo
+	Target       string  `json:"target"`    // webhook URL or Expo push token
+	ExpiresAt    string  `json:"expiresAt"` // RFC3339, defaults to 2 hours from now
+}
+
+// Validate fills in the defaults and turns the request into a subscription
+func (req *AlertRequest) Validate(ctx context.Context, apiData *data.ApiData, now time.Time) (*model.AlertSubscription, error) {
+	if (req.CarParkID == "") == (req.RadiusMeters == 0) {
+		return nil, fmt.Errorf("exactly one of carParkID or radiusMeters is required")
+	}
+	if req.CarParkID != "" {
+		if _, ok := apiData.GetCarParks()[req.CarParkID]; !ok {
+			return nil, fmt.Errorf("unknown car park %s", req.CarParkID)
+		}
+	} else if req.RadiusMeters < 1 || req.RadiusMeters > maxRadiusMeters {
+		return nil, fmt.Errorf("radiusMeters must be between 1 and %d", maxRadiusMeters)
+	} else if req.Latitude == 0 || req.Longitude == 0 {
+		return nil, fmt.Errorf("latitude and longitude are required with radiusMeters")
+	}
+
+	switch req.LotType {
+	case "":
+		req.LotType = model.LotTypeCar
+	case model.LotTypeCar, model.LotTypeMotorcycle, model.LotTypeHeavy, model.LotTypeLorry:
+	default:
+		return nil, fmt.Errorf("lotType must be one of C, Y, H, L")
+	}
+
+	switch req.Condition {
+	case model.AlertAbove:
+		if req.Threshold < 1 || req.Threshold > maxAlertLots {
+			return nil, fmt.Errorf("threshold must be between 1 and %d", maxAlertLots)
+		}
+	case model.AlertBelow:
+		if req.Threshold < 0 || req.Threshold > maxAlertLots {
+			return nil, fmt.Errorf("threshold must be between 0 and %d", maxAlertLots)
+		}
+	default:
+		return nil, fmt.Errorf("condition must be %s or %s", model.AlertAbove, model.AlertBelow)
+	}
+
+	switch req.Channel {
+	case model.AlertChannelWebhook:
+		if err := alerts.ValidateWebhookURL(ctx, req.Target); err != nil {
+			return nil, err
+		}
+	case model.AlertChannelExpo:
+		if !expoPushTokenRegex.MatchString(req.Target) {
+			return nil, fmt.Errorf("target must be an Expo push token for expo alerts")
+		}
+	default:
+		return nil, fmt.Errorf("channel must be %s or %s", model.AlertChannelWebhook, model.AlertChannelExpo)
+	}
+
+	expiresAt := now.Add(defaultAlertTTL)
+	if req.ExpiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("expiresAt must be an RFC3339 timestamp")
+		}
+		expiresAt = parsed
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > maxAlertTTL {
+		return nil, fmt.Errorf("expiresAt must be in the next %s", maxAlertTTL)
+	}
+
+	return &model.AlertSubscription{
+		ID:           uuid.NewString(),
+		CarParkID:    req.CarParkID,
+		Latitude:     req.Latitude,
+		Longitude:    req.Longitude,
+		RadiusMeters: req.RadiusMeters,
+		LotType:      req.LotType,
+		Condition:    req.Condition,
+		Threshold:    req.Threshold,
+		Channel:      req.Channel,
+		Target:       req.Target,
+		CreatedAt:    now,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func CreateAlert(c *fiber.Ctx, apiData *data.ApiData) error {
+	var req AlertRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), webhookLookupTimeout)
+	defer cancel()
+
+	subscription, err := req.Validate(ctx, apiData, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := apiData.Alerts.Store.Save(c.Context(), subscription); err != nil {
+		log.Println("Error saving alert:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error saving alert",
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(subscription)
+}
+
+func GetAlert(c *fiber.Ctx, apiData *data.ApiData) error {
+	subscription, err := apiData.Alerts.Store.Get(c.Context(), c.Params("id"))
+	if err == alerts.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		log.Println("Error getting alert:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error getting alert",
+		})
+	}
+	return c.JSON(subscription)
+}
+
+func DeleteAlert(c *fiber.Ctx, apiData *data.ApiData) error {
+	err := apiData.Alerts.Store.Delete(c.Context(), c.Params("id"))
+	if err == alerts.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		log.Println("Error deleting alert:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error deleting alert",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"log"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/SC2006-Lab/MobileAppProject/history"
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultHistoryRange = 24 * time.Hour
+
+// GetCarParkHistory returns the availability samples of a car park, downsampled to the requested resolution.
+// Query parameters: from / to (RFC3339, default the last 24 hours), resolution (raw, 15m, 1h) and lotType
+func GetCarParkHistory(c *fiber.Ctx, apiData *data.ApiData) error {
+	if apiData.History == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Availability history is not available",
+		})
+	}
+
+	carParkID := c.Params("id")
+
+	to := time.Now()
+	if c.Query("to") != "" {
+		parsed, err := time.Parse(time.RFC3339, c.Query("to"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "to must be an RFC3339 timestamp",
+			})
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultHistoryRange)
+	if c.Query("from") != "" {
+		parsed, err := time.Parse(time.RFC3339, c.Query("from"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "from must be an RFC3339 timestamp",
+			})
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from must be before to",
+		})
+	}
+
+	resolution := c.Query("resolution", history.ResolutionRaw)
+	lotType := c.Query("lotType")
+
+	samples, err := apiData.History.Query(carParkID, lotType, from, to)
+	if err == history.ErrInvalidCarParkID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		log.Println("Error reading availability history:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error reading availability history",
+		})
+	}
+
+	points, err := history.Downsample(samples, resolution)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"carParkID":  carParkID,
+		"from":       from,
+		"to":         to,
+		"resolution": resolution,
+		"history":    points,
+	})
+}
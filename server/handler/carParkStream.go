@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	maxStreamCarParkIDs = 500
+	// a comment is sent this often so dead connections are noticed between refreshes
+	streamHeartbeatInterval = 15 * time.Second
+)
+
+// StreamCarParkAvailability pushes availability deltas over Server-Sent Events.
+// Subscribe with ?ids=ID1,ID2 or ?bbox=minLat,minLng,maxLat,maxLng. A "snapshot" event with the current
+// availability is sent first, then an "availability" event with the changed lots after every refresh.
+// A client that falls too far behind is disconnected, it reconnects and starts again from a new snapshot
+func StreamCarParkAvailability(c *fiber.Ctx, apiData *data.ApiData) error {
+	filter, err := parseAvailabilityFilter(c.Query("ids"), c.Query("bbox"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// Subscribing inside the writer means there is nothing to clean up if it never runs.
+	// The snapshot is taken after subscribing, a refresh in between is then in the snapshot, the events or both
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		subscription := apiData.Hub.Subscribe(filter)
+		defer apiData.Hub.Unsubscribe(subscription)
+		snapshot := availabilitySnapshot(apiData.GetCarParks(), filter)
+		log.Printf("Availability stream opened, %d subscribers", apiData.Hub.Subscribers())
+
+		if err := writeEvent(w, "snapshot", snapshot); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(streamHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case deltas, ok := <-subscription.Events:
+				if !ok {
+					log.Println("Availability stream closed, the client is not keeping up")
+					return
+				}
+				if err := writeEvent(w, "availability", deltas); err != nil {
+					log.Println("Availability stream closed:", err)
+					return
+				}
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				if err := w.Flush(); err != nil {
+					log.Println("Availability stream closed:", err)
+					return
+				}
+			}
+		}
+	}))
+
+	return nil
+}
+
+func writeEvent(w *bufio.Writer, event string, payload any) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payloadJSON)
+	return w.Flush()
+}
+
+func parseAvailabilityFilter(ids, bbox string) (data.AvailabilityFilter, error) {
+	if (ids == "") == (bbox == "") {
+		return data.AvailabilityFilter{}, fmt.Errorf("exactly one of ids or bbox is required")
+	}
+
+	if bbox != "" {
+		bounds := strings.Split(bbox, ",")
+		if len(bounds) != 4 {
+			return data.AvailabilityFilter{}, fmt.Errorf("bbox must be minLat,minLng,maxLat,maxLng")
+		}
+
+		values := make([]float64, 4)
+		for i, bound := range bounds {
+			value, err := strconv.ParseFloat(strings.TrimSpace(bound), 64)
+			if err != nil {
+				return data.AvailabilityFilter{}, fmt.Errorf("bbox must be minLat,minLng,maxLat,maxLng")
+			}
+			values[i] = value
+		}
+		if values[0] > values[2] || values[1] > values[3] {
+			return data.AvailabilityFilter{}, fmt.Errorf("bbox minimum must not exceed its maximum")
+		}
+
+		return data.AvailabilityFilter{BBox: &data.BoundingBox{
+			MinLat: values[0], MinLng: values[1], MaxLat: values[2], MaxLng: values[3],
+		}}, nil
+	}
+
+	carParkIDs := make(map[string]bool)
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			carParkIDs[id] = true
+		}
+	}
+	if len(carParkIDs) == 0 || len(carParkIDs) > maxStreamCarParkIDs {
+		return data.AvailabilityFilter{}, fmt.Errorf("ids must list between 1 and %d car parks", maxStreamCarParkIDs)
+	}
+
+	return data.AvailabilityFilter{CarParkIDs: carParkIDs}, nil
+}
+
+// availabilitySnapshot lists the current lots of the subscribed car parks in the same shape as the deltas
+func availabilitySnapshot(carParks map[string]*model.CarPark, filter data.AvailabilityFilter) []model.AvailabilityDelta {
+	snapshot := []model.AvailabilityDelta{}
+	for carParkID, carPark := range carParks {
+		if !filter.Matches(carParkID, carPark.Latitude, carPark.Longitude) {
+			continue
+		}
+		for lotType, lot := range carPark.LotDetails {
+			snapshot = append(snapshot, model.AvailabilityDelta{
+				CarParkID:      carParkID,
+				LotType:        lotType,
+				TotalLots:      lot.TotalLots,
+				AvailableLots:  lot.AvailableLots,
+				UpdateDatetime: lot.UpdateDatetime,
+				Latitude:       carPark.Latitude,
+				Longitude:      carPark.Longitude,
+			})
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetHealth reports the readiness of every upstream source and whether Redis is bypassed.
+// A degraded server still answers 200, it serves whatever data it has
+func GetHealth(c *fiber.Ctx, apiData *data.ApiData) error {
+	unavailable := apiData.Readiness.Unavailable()
+	cacheStats := apiData.Cache.Stats()
+
+	status := "ok"
+	if len(unavailable) > 0 || cacheStats.Degraded {
+		status = "degraded"
+	}
+
+	return c.JSON(fiber.Map{
+		"status":             status,
+		"unavailableSources": unavailable,
+		"sources":            apiData.Readiness.Statuses(),
+		"cache":              cacheStats,
+	})
+}
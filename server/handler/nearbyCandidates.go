@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/SC2006-Lab/MobileAppProject/cache"
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/SC2006-Lab/MobileAppProject/database"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
+)
+
+const (
+	nearbyCandidatesCacheKeyPrefix = "nearby_candidates"
+	// ~220 m, searches from anywhere in the same cell share their candidates
+	candidateCellSize = 0.002
+)
+
+// candidateCell snaps the searched location to the grid of the candidate cache
+func candidateCell(lat, lng float64) (row, col int) {
+	return int(math.Floor(lat / candidateCellSize)), int(math.Floor(lng / candidateCellSize))
+}
+
+// nearbyCandidates returns the car parks within radiusKm of the searched location.
+// The IDs of every car park that can be in range from somewhere in the cell are cached per car park snapshot, cell and radius,
+// only the exact distances are worked out per request, from the live car park data.
+// The cache only saves work, when it fails the candidates are worked out again
+func nearbyCandidates(ctx context.Context, lat, lng, radiusKm float64, apiData *data.ApiData) []data.NearbyCarPark {
+	row, col := candidateCell(lat, lng)
+	cacheKey := database.Key(nearbyCandidatesCacheKeyPrefix, fmt.Sprintf("%d_%d_%d_%.3f", apiData.CarParkVersion(), row, col, radiusKm))
+
+	ids, err := cachedCandidates(ctx, cacheKey, apiData)
+	if err != nil {
+		if err != cache.ErrMiss {
+			log.Printf("Error getting cached candidates of cell %d_%d: %v", row, col, err)
+		}
+		ids = cellCandidates(row, col, radiusKm, apiData)
+
+		// an empty cell is cheap to search again, and may only be empty because the car parks are not loaded yet
+		if len(ids) > 0 {
+			idsJSON, _ := json.Marshal(ids)
+			if err := apiData.Cache.Set(ctx, cacheKey, idsJSON, cacheExpirationTime); err != nil {
+				log.Printf("Error caching candidates of cell %d_%d: %v", row, col, err)
+			} else {
+				log.Printf("Cached %d candidates of cell %d_%d", len(ids), row, col)
+			}
+		}
+	}
+
+	carParks := apiData.GetCarParks()
+	nearby := make([]data.NearbyCarPark, 0, len(ids))
+	for _, id := range ids {
+		// the car park may have disappeared in a refresh since the candidates were cached
+		carPark, ok := carParks[id]
+		if !ok {
+			continue
+		}
+		distance := utils.CalculateDistance(lat, lng, carPark.Latitude, carPark.Longitude)
+		if distance <= radiusKm {
+			nearby = append(nearby, data.NearbyCarPark{CarPark: carPark, Distance: distance})
+		}
+	}
+	return nearby
+}
+
+func cachedCandidates(ctx context.Context, cacheKey string, apiData *data.ApiData) ([]string, error) {
+	cachedIDs, err := apiData.Cache.Get(ctx, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if err := json.Unmarshal(cachedIDs, &ids); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal cached candidates: %v", err)
+	}
+	return ids, nil
+}
+
+// cellCandidates lists the car parks within radiusKm of any point of the cell,
+// by searching from its centre with the radius grown by half the cell diagonal
+func cellCandidates(row, col int, radiusKm float64, apiData *data.ApiData) []string {
+	centerLat := (float64(row) + 0.5) * candidateCellSize
+	centerLng := (float64(col) + 0.5) * candidateCellSize
+	halfDiagonalKm := utils.CalculateDistance(centerLat, centerLng, float64(row)*candidateCellSize, float64(col)*candidateCellSize)
+
+	candidates := apiData.GetCarParkIndex().WithinRadius(centerLat, centerLng, radiusKm+halfDiagonalKm)
+	ids := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.CarPark.CarParkID)
+	}
+	return ids
+}
@@ -1,8 +1,7 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	_ "fmt"
 	"log"
 	_ "log"
@@ -12,17 +11,14 @@ import (
 	"time"
 
 	"github.com/SC2006-Lab/MobileAppProject/data"
-	"github.com/SC2006-Lab/MobileAppProject/database"
 	"github.com/SC2006-Lab/MobileAppProject/external_services"
 	"github.com/SC2006-Lab/MobileAppProject/model"
 	"github.com/SC2006-Lab/MobileAppProject/utils"
 	"github.com/gofiber/fiber/v2"
-	"github.com/redis/go-redis/v9"
 )
 
 const (
-	nearbyCarParksCacheKeyPrefix = "nearby_carparks:"
-	cacheExpirationTime          = 2 * time.Minute
+	cacheExpirationTime = 2 * time.Minute
 )
 
 // First Method that just keep spawning goroutines/thread to handle each carpark
@@ -185,6 +181,7 @@ func GetNearbyCarParks(c *fiber.Ctx, apiData *data.ApiData) error {
 			Latitude  float64 `json:"latitude"`
 			Longitude float64 `json:"longitude"`
 		} `json:"SearchLocation"`
+		NearbyQuery
 	}
 
 	if err := c.BodyParser(&reqPayload); err != nil {
@@ -193,147 +190,59 @@ func GetNearbyCarParks(c *fiber.Ctx, apiData *data.ApiData) error {
 		})
 	}
 
-	redisClient := database.GetRedisClient()
-	redisCtx := database.GetRedisContext()
-
-	// Cache Key
-	carParkCacheKey := fmt.Sprintf("%s%.6f_%.6f", nearbyCarParksCacheKeyPrefix, reqPayload.SearchedLocation.Latitude, reqPayload.SearchedLocation.Longitude)
+	if err := reqPayload.NearbyQuery.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	cachedCarParkJSON, err := redisClient.Get(redisCtx, carParkCacheKey).Result()
-	if err == nil {
-		log.Println("Found CarParks in Redis")
-		var cachedCarPark []map[string]interface{}
+	weatherArea, _ := apiData.NearestWeatherArea(reqPayload.SearchedLocation.Latitude, reqPayload.SearchedLocation.Longitude)
+	reqPayload.NearbyQuery.applyWeather(weatherArea)
 
-		if err := json.Unmarshal([]byte(cachedCarParkJSON), &cachedCarPark); err == nil {
-			cachedResp := map[string]interface{}{
-				"EV":      []map[string]interface{}{},
-				"CarPark": cachedCarPark,
-			}
+	// Everything below, Redis and every OneMap call included, shares the deadline of the request.
+	// Returning early cancels it so no worker keeps routing for a response nobody will read
+	ctx, cancel := context.WithTimeout(c.UserContext(), utils.GetEnvConfig().NEARBY_REQUEST_TIMEOUT)
+	defer cancel()
 
-			processedEVLots, err := processEVLots(reqPayload.EVLots, reqPayload.CurrentUserLocation, apiData)
-			if err != nil {
-				log.Println("Error processing EV lots:", err)
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Error processing EV lots",
-				})
-			}
+	// Look up the car parks around the searched location, the candidates of its cell are cached.
+	// Routes always start from this user's location, so they are computed per request below
+	radiusKm := reqPayload.NearbyQuery.RadiusMeters / 1000
+	nearbyCarParks := nearbyCandidates(ctx, reqPayload.SearchedLocation.Latitude, reqPayload.SearchedLocation.Longitude, radiusKm, apiData)
 
-			cachedResp["EV"] = processedEVLots
-			return c.JSON(cachedResp)
-		} else {
-			log.Println("Error unmarshalling cached CarParks JSON:", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error processing cached CarParks",
-			})
-		}
-	} else if err != redis.Nil {
-		log.Println("Error getting CarParks from Redis:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error getting CarParks from Redis",
-		})
-	}
-
-	// If couldnt find in cache, process the data
-	// Cache the processed data for future requests
-	// Create channels for results and errors
+	// Routing cannot fail the request, a route OneMap does not return is estimated instead,
+	// so both lists are always complete once their goroutine is done
 	processedEVLotsChan := make(chan []map[string]interface{}, 1)
 	processedCarParkChan := make(chan []map[string]interface{}, 1)
-	errChan := make(chan error, 2)
 
 	// Process EV lots
 	go func() {
-		processedEVLots, err := processEVLots(reqPayload.EVLots, reqPayload.CurrentUserLocation, apiData)
-		if err != nil {
-			errChan <- err
-			return
-		}
-		processedEVLotsChan <- processedEVLots
+		processedEVLotsChan <- processEVLots(ctx, reqPayload.EVLots, reqPayload.CurrentUserLocation, apiData)
 	}()
 
 	// Process car parks
 	go func() {
-		// Pre-filter car parks by distance before detailed processing
-		nearbyCarParks := preFilterCarParks(apiData.CarPark, reqPayload.SearchedLocation, 2.5) // Slightly larger radius for pre-filtering
-
-		processedCarPark, err := processCarParks(nearbyCarParks, reqPayload.CurrentUserLocation, reqPayload.SearchedLocation, apiData)
-		if err != nil {
-			errChan <- err
-			return
-		}
-		processedCarParkChan <- processedCarPark
+		processedCarParkChan <- processCarParks(ctx, nearbyCarParks, reqPayload.CurrentUserLocation, reqPayload.NearbyQuery, apiData)
 	}()
 
-	// Collect results
-	var processedEVLots, processedCarPark []map[string]interface{}
-	var errCount int
-
-	// Wait for both goroutines to complete or return early on errors
-	for range 2 {
-		select {
-		case <-errChan:
-			errCount++
-			if errCount == 2 {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Error processing data",
-				})
-			}
-		case result := <-processedEVLotsChan:
-			processedEVLots = result
-		case result := <-processedCarParkChan:
-			processedCarPark = result
-		}
-	}
+	// Wait for both goroutines to complete
+	processedEVLots := <-processedEVLotsChan
+	processedCarPark := <-processedCarParkChan
 
 	response := map[string]interface{}{
 		"EV":      processedEVLots,
 		"CarPark": processedCarPark,
-	}
-
-	// Caching the carpark data
-	carParkJSON, err := json.Marshal(processedCarPark)
-	if err != nil {
-		log.Println("Error marshalling CarParks to JSON:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error marshalling CarParks for caching",
-		})
-	} else {
-		log.Println("Caching CarParks in Redis")
-		if err := redisClient.Set(redisCtx, carParkCacheKey, carParkJSON, cacheExpirationTime).Err(); err != nil {
-			log.Println("Error caching CarParks:", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error caching CarParks",
-			})
-		} else {
-			log.Println("Cached CarParks in Redis")
-			log.Println("CarParks data key: ", carParkCacheKey)
-		}
+		// e.g. carParks while the server is still retrying an upstream that was down at boot
+		"unavailableSources": apiData.Readiness.Unavailable(),
 	}
 
 	log.Println("Returning response to client")
 	return c.JSON(response)
 }
 
-// Pre-filter carparks based on distance to reduce the number of detailed computations
-func preFilterCarParks(carParks map[string]*model.CarPark, searchedLocation struct {
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-}, maxDistance float64) map[string]*model.CarPark {
-	result := make(map[string]*model.CarPark)
-
-	for id, carPark := range carParks {
-		if distance := utils.CalculateDistance(searchedLocation.Latitude, searchedLocation.Longitude,
-			carPark.Latitude, carPark.Longitude); distance <= maxDistance {
-			result[id] = carPark
-		}
-	}
-
-	return result
-}
-
-func processEVLots(evLots []*model.EVLot, currentUserLocation struct {
+func processEVLots(ctx context.Context, evLots []*model.EVLot, currentUserLocation struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
-}, apiData *data.ApiData) ([]map[string]interface{}, error) {
+}, apiData *data.ApiData) []map[string]interface{} {
 	// Set a reasonable worker limit to avoid overwhelming the system
 	const maxWorkers = 10
 	workerLimit := min(len(evLots), maxWorkers)
@@ -341,28 +250,17 @@ func processEVLots(evLots []*model.EVLot, currentUserLocation struct {
 	// Create a buffered channel for work distribution
 	jobChan := make(chan *model.EVLot, len(evLots))
 	resultChan := make(chan map[string]interface{}, len(evLots))
-	errChan := make(chan error, 1)
 
-	// Use a separate client for each worker for better connection reuse
-	oneMapToken := *apiData.OneMapToken
+	// Every worker shares the pooled client, so connections to OneMap are reused across requests
+	client := apiData.HTTPClient
+	oneMapTokens := apiData.OneMapToken
+	routes := apiData.Routes
 
 	// Launch workers
 	for i := 0; i < workerLimit; i++ {
 		go func() {
-			client := &http.Client{
-				Transport: &http.Transport{
-					MaxIdleConnsPerHost: 10,
-					IdleConnTimeout:     30 * time.Second,
-				},
-			}
-
 			for evLot := range jobChan {
-				processedLot, err := processEVLot(evLot, currentUserLocation, oneMapToken, client)
-				if err != nil {
-					errChan <- err
-					return
-				}
-				resultChan <- processedLot
+				resultChan <- processEVLot(ctx, evLot, currentUserLocation, routes, oneMapTokens, client)
 			}
 		}()
 	}
@@ -379,22 +277,17 @@ func processEVLots(evLots []*model.EVLot, currentUserLocation struct {
 	processedEVLots := make([]map[string]interface{}, 0, len(evLots))
 
 	for i := 0; i < len(evLots); i++ {
-		select {
-		case err := <-errChan:
-			return nil, err
-		case result := <-resultChan:
-			processedEVLots = append(processedEVLots, result)
-		}
+		processedEVLots = append(processedEVLots, <-resultChan)
 	}
 
-	return processedEVLots, nil
+	return processedEVLots
 }
 
 // Process a single EV lot
-func processEVLot(evLot *model.EVLot, currentUserLocation struct {
+func processEVLot(ctx context.Context, evLot *model.EVLot, currentUserLocation struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
-}, oneMapToken string, client *http.Client) (map[string]interface{}, error) {
+}, routes *external_services.RouteCache, oneMapTokens *external_services.TokenManager, client *http.Client) map[string]interface{} {
 	chargers := make([]map[string]string, 0, len(evLot.EVChargerOptions.ConnectorAggregation))
 
 	for _, connector := range evLot.EVChargerOptions.ConnectorAggregation {
@@ -413,19 +306,17 @@ func processEVLot(evLot *model.EVLot, currentUserLocation struct {
 		})
 	}
 
-	routeInfo, err := external_services.ComputeRoute(
+	routeInfo, routeStatus := computeRouteOrEstimate(
+		ctx,
 		currentUserLocation.Latitude,
 		currentUserLocation.Longitude,
 		evLot.Location.Latitude,
 		evLot.Location.Longitude,
-		oneMapToken,
+		routes,
+		oneMapTokens,
 		client,
 	)
 
-	if err != nil {
-		return nil, err
-	}
-
 	return map[string]interface{}{
 		"formattedAddress": evLot.FormattedAddress,
 		"location": map[string]float64{
@@ -441,103 +332,173 @@ func processEVLot(evLot *model.EVLot, currentUserLocation struct {
 			"duration": strconv.FormatFloat(utils.ConvertSecondsToMinutes(routeInfo.Duration), 'f', 0, 64),
 			"polyline": routeInfo.Polyline,
 		},
-	}, nil
+		"routeStatus": routeStatus,
+	}
+}
+
+// computeRouteOrEstimate routes through OneMap and falls back to a straight-line estimate when that fails,
+// so one failing route never drops the whole response. Once ctx is done OneMap is not called anymore
+func computeRouteOrEstimate(ctx context.Context, originLat, originLng, destLat, destLng float64, routes *external_services.RouteCache,
+	oneMapTokens *external_services.TokenManager, client *http.Client) (*model.RouteInfo, string) {
+	if ctx.Err() != nil {
+		return external_services.EstimateRoute(originLat, originLng, destLat, destLng), model.RouteStatusEstimated
+	}
+
+	routeInfo, err := routes.ComputeRoute(ctx, originLat, originLng, destLat, destLng, oneMapTokens, client)
+	if err != nil {
+		log.Printf("Error computing route to %f,%f, using a straight-line estimate: %v", destLat, destLng, err)
+		return external_services.EstimateRoute(originLat, originLng, destLat, destLng), model.RouteStatusEstimated
+	}
+	return routeInfo, model.RouteStatusOK
 }
 
-func processCarParks(carParks map[string]*model.CarPark, currentUserLocation, searchedLocation struct {
+func processCarParks(ctx context.Context, nearbyCarParks []data.NearbyCarPark, currentUserLocation struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
-}, apiData *data.ApiData) ([]map[string]interface{}, error) {
+}, query NearbyQuery, apiData *data.ApiData) []map[string]interface{} {
 	// Set a reasonable worker limit
 	const maxWorkers = 10
-	carParkList := make([]*model.CarPark, 0, len(carParks))
+	carParkList := make([]sortableCarPark, 0, len(nearbyCarParks))
 
-	for _, carPark := range carParks {
-		if utils.CalculateDistance(searchedLocation.Latitude, searchedLocation.Longitude, carPark.Latitude, carPark.Longitude) <= 2 {
-			carParkList = append(carParkList, carPark)
+	now := time.Now()
+	for _, nearby := range nearbyCarParks {
+		if !matchesAttributes(nearby.CarPark, query, now) {
+			continue
 		}
+
+		// Drop car parks without any lot for the requested vehicle type before routing them
+		lotDetails := filterLots(nearby.CarPark.LotDetails, query.VehicleType)
+		if len(lotDetails) == 0 {
+			continue
+		}
+
+		carParkList = append(carParkList, sortableCarPark{
+			carPark:           nearby.CarPark,
+			lotDetails:        lotDetails,
+			distance:          nearby.Distance,
+			availabilityRatio: availabilityRatio(lotDetails),
+			price:             estimateFee(nearby.CarPark, query),
+			sheltered:         nearby.CarPark.IsSheltered(),
+		})
+	}
+
+	// Only drive time needs the route to sort, otherwise truncate first to save OneMap calls
+	if query.SortBy != SortByDriveTime {
+		carParkList = sortAndLimit(carParkList, query)
 	}
 
 	workerLimit := min(len(carParkList), maxWorkers)
 
 	// Create channels for work distribution
-	jobChan := make(chan *model.CarPark, len(carParkList))
-	resultChan := make(chan map[string]interface{}, len(carParkList))
-	errChan := make(chan error, 1)
+	// Workers fill in carParkList[i] directly, each index is only touched by one worker
+	jobChan := make(chan int, len(carParkList))
+	doneChan := make(chan struct{}, len(carParkList))
 
-	oneMapToken := *apiData.OneMapToken
+	client := apiData.HTTPClient
+	oneMapTokens := apiData.OneMapToken
+	routes := apiData.Routes
 
 	// Launch workers
 	for i := 0; i < workerLimit; i++ {
 		go func() {
-			client := &http.Client{
-				Transport: &http.Transport{
-					MaxIdleConnsPerHost: 10,
-					IdleConnTimeout:     30 * time.Second,
-				},
-			}
-
-			for carPark := range jobChan {
+			for idx := range jobChan {
+				carPark := carParkList[idx].carPark
+				lotDetails := carParkList[idx].lotDetails
+				rates := make(map[string]*model.LotRates)
+				for lotType := range lotDetails {
+					if lotRates, ok := carPark.Rates[lotType]; ok {
+						rates[lotType] = lotRates
+					}
+				}
 				processedCarPark := map[string]interface{}{
 					"carParkID":   carPark.CarParkID,
 					"address":     carPark.Address,
 					"carParkType": carPark.CarParkType,
 					"latitude":    carPark.Latitude,
 					"longitude":   carPark.Longitude,
-					"lotDetails":  make(map[string]interface{}),
+
+					"gantryHeight":        carPark.GantryHeight,
+					"typeOfParkingSystem": carPark.TypeOfParkingSystem,
+					"shortTermParking":    carPark.ShortTermParking,
+					"freeParking":         carPark.FreeParking,
+					"freeParkingNow":      model.IsFreeParkingAt(carPark.FreeParking, now),
+					"nightParking":        carPark.NightParking,
+					"carParkDecks":        carPark.CarParkDecks,
+					"carParkBasement":     carPark.CarParkBasement,
+
+					"sheltered":       carPark.Sheltered(), // null when the car park type is unknown
+					"weatherForecast": query.weatherForecast,
+
+					"rates":        rates,
+					"estimatedFee": carParkList[idx].price,
 				}
 
-				for lotType, lot := range carPark.LotDetails {
-					processedCarPark["lotDetails"].(map[string]interface{})[lotType] = map[string]string{
-						"totalLots":     lot.TotalLots,
-						"availableLots": lot.AvailableLots,
+				lotDetailsResp := make(map[string]map[string]string, len(lotDetails))
+				for lotType, lot := range lotDetails {
+					lotDetailsResp[lotType] = map[string]string{
+						"totalLots":      lot.TotalLots,
+						"availableLots":  lot.AvailableLots,
+						"updateDatetime": lot.UpdateDatetime,
 					}
 				}
+				processedCarPark["lotDetails"] = lotDetailsResp
 
-				routeInfo, err := external_services.ComputeRoute(
+				routeInfo, routeStatus := computeRouteOrEstimate(
+					ctx,
 					currentUserLocation.Latitude,
 					currentUserLocation.Longitude,
 					carPark.Latitude,
 					carPark.Longitude,
-					oneMapToken,
+					routes,
+					oneMapTokens,
 					client,
 				)
 
-				if err != nil {
-					errChan <- err
-					return
-				}
-
 				processedCarPark["routeInfo"] = map[string]string{
 					"distance": strconv.FormatFloat(utils.ConvertMeterToKm(routeInfo.Distance), 'f', 1, 64),
 					"duration": strconv.FormatFloat(utils.ConvertSecondsToMinutes(routeInfo.Duration), 'f', 0, 64),
 					"polyline": routeInfo.Polyline,
 				}
+				processedCarPark["routeStatus"] = routeStatus
+
+				// Current availability says little after a long drive, so predict it for the arrival time,
+				// the one the fee is estimated at when the client gave it
+				arrival := now.Add(time.Duration(routeInfo.Duration) * time.Second)
+				if query.Arrival != "" {
+					arrival = query.arrivalTime
+				}
+				for lotType, lotResp := range lotDetailsResp {
+					if predicted, ok := apiData.Forecaster.Predict(carPark.CarParkID, lotType, arrival); ok {
+						lotResp["predictedAvailableLotsAtArrival"] = strconv.Itoa(predicted)
+					}
+				}
 
-				resultChan <- processedCarPark
+				carParkList[idx].duration = routeInfo.Duration
+				carParkList[idx].result = processedCarPark
+				doneChan <- struct{}{}
 			}
 		}()
 	}
 
 	// Send jobs to workers
 	go func() {
-		for _, carPark := range carParkList {
-			jobChan <- carPark
+		for idx := range carParkList {
+			jobChan <- idx
 		}
 		close(jobChan)
 	}()
 
-	// Collect results
-	processedCarParks := make([]map[string]interface{}, 0, len(carParkList))
-
+	// Wait for every car park to be routed
 	for i := 0; i < len(carParkList); i++ {
-		select {
-		case err := <-errChan:
-			return nil, err
-		case result := <-resultChan:
-			processedCarParks = append(processedCarParks, result)
-		}
+		<-doneChan
+	}
+
+	carParkList = sortAndLimit(carParkList, query)
+
+	processedCarParks := make([]map[string]interface{}, 0, len(carParkList))
+	for _, carPark := range carParkList {
+		processedCarParks = append(processedCarParks, carPark.result)
 	}
 
-	return processedCarParks, nil
+	return processedCarParks
 }
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+const (
+	defaultRadiusMeters = 2000
+	maxRadiusMeters     = 10000
+	defaultResultLimit  = 50
+	maxResultLimit      = 200
+	maxGantryHeight     = 10.0
+	maxDurationMinutes  = 3 * 24 * 60
+	// rates and the weekly forecast slots repeat every week, nothing further ahead can be estimated
+	maxArrivalAhead = 7 * 24 * time.Hour
+	// an arrival this far in the past is still accepted, for clients whose clock is a little behind
+	arrivalClockSkew = 5 * time.Minute
+	// used to rank by price when no duration was given
+	defaultPriceDurationMinutes = 60
+
+	SortByDistance     = "distance"
+	SortByDriveTime    = "driveTime"
+	SortByAvailability = "availability"
+	SortByPrice        = "price"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// NearbyQuery holds the optional search options of /api/carpark/nearby
+type NearbyQuery struct {
+	RadiusMeters float64 `json:"radiusMeters"`
+	Limit        int     `json:"limit"`
+	SortBy       string  `json:"sortBy"`
+	Order        string  `json:"order"`
+	VehicleType  string  `json:"vehicleType"`
+
+	// HDB attribute filters, car parks that do not report the attribute are excluded when the filter is set
+	MinGantryHeight float64 `json:"minGantryHeight"` // metres
+	FreeParkingNow  bool    `json:"freeParkingNow"`
+	NightParking    bool    `json:"nightParking"`
+
+	// Fee estimation and availability prediction, Arrival is RFC3339 and defaults to now plus the drive time.
+	// No fee is estimated without a duration
+	Arrival         string    `json:"arrival"`
+	DurationMinutes int       `json:"durationMinutes"`
+	arrivalTime     time.Time // parsed Arrival
+
+	// Filled from the forecast area of the searched location, not by the client
+	weatherForecast string
+	preferSheltered bool // rain is forecast, rank sheltered car parks first
+}
+
+// Validate fills in the defaults and rejects out of range values
+func (query *NearbyQuery) Validate() error {
+	if query.RadiusMeters == 0 {
+		query.RadiusMeters = defaultRadiusMeters
+	}
+	if query.RadiusMeters < 1 || query.RadiusMeters > maxRadiusMeters {
+		return fmt.Errorf("radiusMeters must be between 1 and %d", maxRadiusMeters)
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultResultLimit
+	}
+	if query.Limit < 0 || query.Limit > maxResultLimit {
+		return fmt.Errorf("limit must be between 1 and %d", maxResultLimit)
+	}
+
+	switch query.SortBy {
+	case "":
+		query.SortBy = SortByDistance
+	case SortByDistance, SortByDriveTime, SortByAvailability, SortByPrice:
+	default:
+		return fmt.Errorf("sortBy must be one of %s, %s, %s, %s", SortByDistance, SortByDriveTime, SortByAvailability, SortByPrice)
+	}
+
+	switch query.Order {
+	case "":
+		// most available first, everything else closest / cheapest first
+		if query.SortBy == SortByAvailability {
+			query.Order = OrderDesc
+		} else {
+			query.Order = OrderAsc
+		}
+	case OrderAsc, OrderDesc:
+	default:
+		return fmt.Errorf("order must be %s or %s", OrderAsc, OrderDesc)
+	}
+
+	if _, ok := model.VehicleLotTypes[query.VehicleType]; query.VehicleType != "" && !ok {
+		return fmt.Errorf("vehicleType must be one of %s, %s, %s", model.VehicleTypeCar, model.VehicleTypeMotorcycle, model.VehicleTypeHeavy)
+	}
+
+	if query.MinGantryHeight < 0 || query.MinGantryHeight > maxGantryHeight {
+		return fmt.Errorf("minGantryHeight must be between 0 and %.1f", maxGantryHeight)
+	}
+
+	now := time.Now()
+	query.arrivalTime = now
+	if query.Arrival != "" {
+		arrival, err := time.Parse(time.RFC3339, query.Arrival)
+		if err != nil {
+			return fmt.Errorf("arrival must be an RFC3339 timestamp")
+		}
+		if arrival.Before(now.Add(-arrivalClockSkew)) || arrival.After(now.Add(maxArrivalAhead)) {
+			return fmt.Errorf("arrival must be between now and %d days ahead", int(maxArrivalAhead.Hours()/24))
+		}
+		query.arrivalTime = arrival
+	}
+
+	if query.DurationMinutes < 0 || query.DurationMinutes > maxDurationMinutes {
+		return fmt.Errorf("durationMinutes must be between 0 and %d", maxDurationMinutes)
+	}
+	if query.DurationMinutes == 0 && query.SortBy == SortByPrice {
+		query.DurationMinutes = defaultPriceDurationMinutes
+	}
+
+	return nil
+}
+
+// applyWeather takes the forecast of the searched location into account, area is nil when there is no forecast
+func (query *NearbyQuery) applyWeather(area *model.WeatherAreaInfo) {
+	if area == nil {
+		return
+	}
+	query.weatherForecast = area.Weather
+	query.preferSheltered = area.IsRaining()
+}
+
+// feeLotType is the lot type whose rates are used to estimate the fee, cars unless a vehicle type was given
+func (query *NearbyQuery) feeLotType() string {
+	if lotTypes, ok := model.VehicleLotTypes[query.VehicleType]; ok {
+		return lotTypes[0]
+	}
+	return model.LotTypeCar
+}
+
+// estimateFee returns the fee of the requested stay, nil when no duration was asked or the rates are unknown
+func estimateFee(carPark *model.CarPark, query NearbyQuery) *float64 {
+	if query.DurationMinutes == 0 {
+		return nil
+	}
+
+	rates, ok := carPark.Rates[query.feeLotType()]
+	if !ok {
+		return nil
+	}
+
+	fee := rates.EstimateFee(query.arrivalTime, time.Duration(query.DurationMinutes)*time.Minute)
+	return &fee
+}
+
+// matchesAttributes applies the HDB attribute filters to a car park
+func matchesAttributes(carPark *model.CarPark, query NearbyQuery, now time.Time) bool {
+	if query.MinGantryHeight > 0 {
+		// a height of 0 means there is no gantry at all
+		if carPark.GantryHeight == nil || (*carPark.GantryHeight != 0 && *carPark.GantryHeight < query.MinGantryHeight) {
+			return false
+		}
+	}
+
+	if query.FreeParkingNow && !model.IsFreeParkingAt(carPark.FreeParking, now) {
+		return false
+	}
+
+	if query.NightParking && (carPark.NightParking == nil || !*carPark.NightParking) {
+		return false
+	}
+
+	return true
+}
+
+// filterLots keeps only the lots the vehicle type can park in, every lot if no vehicle type was given
+func filterLots(lotDetails map[string]*model.Lot, vehicleType string) map[string]*model.Lot {
+	if vehicleType == "" {
+		return lotDetails
+	}
+
+	filtered := make(map[string]*model.Lot)
+	for _, lotType := range model.VehicleLotTypes[vehicleType] {
+		if lot, ok := lotDetails[lotType]; ok {
+			filtered[lotType] = lot
+		}
+	}
+	return filtered
+}
+
+// sortableCarPark keeps the numeric sort keys next to a car park and its formatted response
+type sortableCarPark struct {
+	carPark           *model.CarPark
+	lotDetails        map[string]*model.Lot // lots matching the requested vehicle type
+	distance          float64               // straight line from the searched location, km
+	duration          float64               // drive time, seconds
+	availabilityRatio float64               // available / total over all lot types, -1 when unknown
+	price             *float64              // nil when unknown
+	sheltered         bool
+	result            map[string]interface{}
+}
+
+// sortAndLimit orders the car parks deterministically and truncates them to the query limit.
+// Car parks with an unknown sort key always come last, ties are broken by distance then car park ID.
+// When rain is forecast sheltered car parks are ranked ahead of the rest, each group sorted on the requested key
+func sortAndLimit(carParks []sortableCarPark, query NearbyQuery) []sortableCarPark {
+	// compare returns -1, 0 or 1 on the requested key
+	compare := func(a, b sortableCarPark) int {
+		switch query.SortBy {
+		case SortByDriveTime:
+			return cmp.Compare(a.duration, b.duration)
+		case SortByAvailability:
+			return cmp.Compare(a.availabilityRatio, b.availabilityRatio)
+		case SortByPrice:
+			if a.price == nil || b.price == nil {
+				return 0
+			}
+			return cmp.Compare(*a.price, *b.price)
+		default:
+			return cmp.Compare(a.distance, b.distance)
+		}
+	}
+
+	unknown := func(carPark sortableCarPark) bool {
+		switch query.SortBy {
+		case SortByAvailability:
+			return carPark.availabilityRatio < 0
+		case SortByPrice:
+			return carPark.price == nil
+		}
+		return false
+	}
+
+	sort.SliceStable(carParks, func(i, j int) bool {
+		a, b := carParks[i], carParks[j]
+		if unknown(a) != unknown(b) {
+			return !unknown(a)
+		}
+
+		if query.preferSheltered && a.sheltered != b.sheltered {
+			return a.sheltered
+		}
+
+		if result := compare(a, b); result != 0 {
+			if query.Order == OrderDesc {
+				return result > 0
+			}
+			return result < 0
+		}
+
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		return a.carPark.CarParkID < b.carPark.CarParkID
+	})
+
+	if len(carParks) > query.Limit {
+		carParks = carParks[:query.Limit]
+	}
+	return carParks
+}
+
+// availabilityRatio sums the lots that have both counts known, -1 if none does
+func availabilityRatio(lotDetails map[string]*model.Lot) float64 {
+	var available, total int
+	for _, lot := range lotDetails {
+		availableLots, errAvailable := strconv.Atoi(lot.AvailableLots)
+		totalLots, errTotal := strconv.Atoi(lot.TotalLots)
+		if errAvailable != nil || errTotal != nil || totalLots <= 0 {
+			continue
+		}
+		available += availableLots
+		total += totalLots
+	}
+
+	if total == 0 {
+		return -1
+	}
+	return float64(available) / float64(total)
+}
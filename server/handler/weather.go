@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/SC2006-Lab/MobileAppProject/data"
+	"github.com/SC2006-Lab/MobileAppProject/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetNearestWeather returns the two-hour forecast of the area closest to ?lat=&lng=
+func GetNearestWeather(c *fiber.Ctx, apiData *data.ApiData) error {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "lat and lng must be valid coordinates",
+		})
+	}
+
+	area, distance := apiData.NearestWeatherArea(lat, lng)
+	if area == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Weather forecast is not available",
+		})
+	}
+
+	return c.JSON(map[string]interface{}{
+		"name":            area.Name,
+		"latitude":        area.Latitude,
+		"longitude":       area.Longitude,
+		"forecast":        area.Weather,
+		"validFrom":       area.ValidFrom,
+		"validTo":         area.ValidTo,
+		"updateTimestamp": area.UpdateTimestamp,
+		"distanceKm":      distance,
+	})
+}
+
+// GetWeatherAreas returns the two-hour forecast of every area sorted by name
+func GetWeatherAreas(c *fiber.Ctx, apiData *data.ApiData) error {
+	weather := apiData.GetWeather()
+
+	areas := make([]*model.WeatherAreaInfo, 0, len(weather))
+	for _, area := range weather {
+		areas = append(areas, area)
+	}
+	sort.Slice(areas, func(i, j int) bool {
+		return areas[i].Name < areas[j].Name
+	})
+
+	return c.JSON(fiber.Map{
+		"areas": areas,
+	})
+}
@@ -0,0 +1,78 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+// Resolutions accepted by Downsample
+const (
+	ResolutionRaw    = "raw"
+	Resolution15Min  = "15m"
+	ResolutionHourly = "1h"
+)
+
+var resolutionBuckets = map[string]time.Duration{
+	ResolutionRaw:    0,
+	Resolution15Min:  15 * time.Minute,
+	ResolutionHourly: time.Hour,
+}
+
+// Downsample aggregates the samples per lot type into buckets of the resolution.
+// Raw keeps one point per sample
+func Downsample(samples []model.AvailabilitySample, resolution string) ([]model.AvailabilityPoint, error) {
+	bucketSize, ok := resolutionBuckets[resolution]
+	if !ok {
+		return nil, fmt.Errorf("resolution must be one of %s, %s, %s", ResolutionRaw, Resolution15Min, ResolutionHourly)
+	}
+
+	type bucketKey struct {
+		start   int64
+		lotType string
+	}
+	buckets := make(map[bucketKey]*model.AvailabilityPoint)
+	sums := make(map[bucketKey]int)
+
+	for _, sample := range samples {
+		start := sample.Time
+		if bucketSize > 0 {
+			start = sample.Time.Truncate(bucketSize)
+		}
+		key := bucketKey{start.Unix(), sample.LotType}
+
+		point, ok := buckets[key]
+		if !ok {
+			point = &model.AvailabilityPoint{
+				Time:             start,
+				LotType:          sample.LotType,
+				MinAvailableLots: math.MaxInt,
+				MaxAvailableLots: math.MinInt,
+			}
+			buckets[key] = point
+		}
+
+		point.Samples++
+		sums[key] += sample.AvailableLots
+		point.MinAvailableLots = min(point.MinAvailableLots, sample.AvailableLots)
+		point.MaxAvailableLots = max(point.MaxAvailableLots, sample.AvailableLots)
+		point.TotalLots = max(point.TotalLots, sample.TotalLots)
+	}
+
+	points := make([]model.AvailabilityPoint, 0, len(buckets))
+	for key, point := range buckets {
+		point.AvgAvailableLots = math.Round(float64(sums[key])/float64(point.Samples)*10) / 10
+		points = append(points, *point)
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		if !points[i].Time.Equal(points[j].Time) {
+			return points[i].Time.Before(points[j].Time)
+		}
+		return points[i].LotType < points[j].LotType
+	})
+	return points, nil
+}
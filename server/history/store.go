@@ -0,0 +1,264 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SC2006-Lab/MobileAppProject/model"
+)
+
+// Files are locked through a fixed set of stripes, a writer only ever holds the lock of the file it writes
+const fileLockStripes = 64
+
+// Car park IDs are used as file names, so anything but plain IDs is rejected
+var carParkIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var ErrInvalidCarParkID = fmt.Errorf("invalid car park ID")
+
+// Store is an embedded availability time-series store.
+// Every car park has its own append only file with one "unix,lotType,available,total" line per lot type and refresh,
+// so reading the history of a car park never has to scan the others
+type Store struct {
+	dir       string
+	retention time.Duration
+
+	fileLocks [fileLockStripes]sync.RWMutex
+
+	compactMu     sync.Mutex // one compaction at a time
+	lastCompacted time.Time
+}
+
+func NewStore(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("fail to create history directory: %v", err)
+	}
+	return &Store{dir: dir, retention: retention}, nil
+}
+
+func (store *Store) path(carParkID string) string {
+	return filepath.Join(store.dir, carParkID+".csv")
+}
+
+// fileLock guards the file of the car park
+func (store *Store) fileLock(carParkID string) *sync.RWMutex {
+	hash := fnv.New32a()
+	hash.Write([]byte(carParkID))
+	return &store.fileLocks[hash.Sum32()%fileLockStripes]
+}
+
+// Append records the availability of every car park at the given time.
+// Samples older than the retention are dropped once a day.
+// Only the file being written is locked, queries of other car parks go on meanwhile
+func (store *Store) Append(carParks map[string]*model.CarPark, at time.Time) error {
+	var failed int
+	for carParkID, carPark := range carParks {
+		if !carParkIDRegex.MatchString(carParkID) {
+			continue
+		}
+
+		var lines strings.Builder
+		for lotType, lot := range carPark.LotDetails {
+			available, err := strconv.Atoi(lot.AvailableLots)
+			if err != nil {
+				continue
+			}
+			total, _ := strconv.Atoi(lot.TotalLots)
+			fmt.Fprintf(&lines, "%d,%s,%d,%d\n", at.Unix(), lotType, available, total)
+		}
+		if lines.Len() == 0 {
+			continue
+		}
+
+		lock := store.fileLock(carParkID)
+		lock.Lock()
+		err := appendFile(store.path(carParkID), lines.String())
+		lock.Unlock()
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("fail to write the history of %d car parks", failed)
+	}
+
+	store.compactMu.Lock()
+	defer store.compactMu.Unlock()
+	if at.Sub(store.lastCompacted) >= 24*time.Hour {
+		store.lastCompacted = at
+		if err := store.compact(at.Add(-store.retention)); err != nil {
+			log.Printf("Error compacting availability history: %v", err)
+		}
+	}
+	return nil
+}
+
+func appendFile(path, content string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	return err
+}
+
+// Query returns the samples of a car park between from and to (inclusive), oldest first.
+// An empty lotType returns every lot type
+func (store *Store) Query(carParkID, lotType string, from, to time.Time) ([]model.AvailabilitySample, error) {
+	if !carParkIDRegex.MatchString(carParkID) {
+		return nil, ErrInvalidCarParkID
+	}
+
+	lock := store.fileLock(carParkID)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	samples := []model.AvailabilitySample{}
+	err := readSamples(store.path(carParkID), func(sample model.AvailabilitySample) {
+		if sample.Time.Before(from) || sample.Time.After(to) {
+			return
+		}
+		if lotType != "" && sample.LotType != lotType {
+			return
+		}
+		samples = append(samples, sample)
+	})
+	if os.IsNotExist(err) {
+		return samples, nil
+	}
+	return samples, err
+}
+
+// Scan calls fn for every stored sample newer than since, car park by car park and oldest first.
+// Files are locked one at a time so refreshes are not blocked for the whole scan
+func (store *Store) Scan(since time.Time, fn func(carParkID string, sample model.AvailabilitySample)) error {
+	entries, err := os.ReadDir(store.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		carParkID, ok := strings.CutSuffix(entry.Name(), ".csv")
+		if entry.IsDir() || !ok {
+			continue
+		}
+
+		lock := store.fileLock(carParkID)
+		lock.RLock()
+		err := readSamples(store.path(carParkID), func(sample model.AvailabilitySample) {
+			if !sample.Time.Before(since) {
+				fn(carParkID, sample)
+			}
+		})
+		lock.RUnlock()
+
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSamples(path string, fn func(model.AvailabilitySample)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if sample, ok := parseSample(scanner.Text()); ok {
+			fn(sample)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseSample(line string) (model.AvailabilitySample, bool) {
+	fields := strings.Split(line, ",")
+	if len(fields) != 4 {
+		return model.AvailabilitySample{}, false
+	}
+
+	unix, errTime := strconv.ParseInt(fields[0], 10, 64)
+	available, errAvailable := strconv.Atoi(fields[2])
+	total, errTotal := strconv.Atoi(fields[3])
+	if errTime != nil || errAvailable != nil || errTotal != nil {
+		return model.AvailabilitySample{}, false
+	}
+
+	return model.AvailabilitySample{
+		Time:          time.Unix(unix, 0),
+		LotType:       fields[1],
+		AvailableLots: available,
+		TotalLots:     total,
+	}, true
+}
+
+// compact rewrites every file without the samples older than cutoff, locking one file at a time
+func (store *Store) compact(cutoff time.Time) error {
+	entries, err := os.ReadDir(store.dir)
+	if err != nil {
+		return err
+	}
+
+	cutoffUnix := cutoff.Unix()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".csv") {
+			continue
+		}
+
+		lock := store.fileLock(strings.TrimSuffix(entry.Name(), ".csv"))
+		lock.Lock()
+		err := compactFile(filepath.Join(store.dir, entry.Name()), cutoffUnix)
+		lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compactFile(path string, cutoffUnix int64) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var kept strings.Builder
+	for _, line := range strings.Split(string(content), "\n") {
+		unix, err := strconv.ParseInt(strings.SplitN(line, ",", 2)[0], 10, 64)
+		if err != nil || unix < cutoffUnix {
+			continue
+		}
+		kept.WriteString(line)
+		kept.WriteString("\n")
+	}
+
+	if kept.Len() == len(content) {
+		return nil
+	}
+	if kept.Len() == 0 {
+		os.Remove(path)
+		return nil
+	}
+
+	// write then rename so a crash never leaves a half written file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(kept.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
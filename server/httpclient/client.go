@@ -0,0 +1,189 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	baseBackoff = 200 * time.Millisecond
+	maxBackoff  = 5 * time.Second
+	dialTimeout = 10 * time.Second
+	// safety net for callers without a deadline, each call is normally bounded by its context
+	clientTimeout = time.Minute
+)
+
+type Config struct {
+	UserAgent  string
+	MaxPerHost int // concurrent requests per host, 0 means no cap
+	MaxRetries int // retries after the first attempt on 5xx / 429 / network errors
+}
+
+// New returns the client every outbound call shares.
+// Connections are pooled per host, idempotent requests are retried with jittered backoff on 5xx, 429 and network errors,
+// and each host only gets MaxPerHost requests in flight at a time
+func New(config Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   max(config.MaxPerHost, 10),
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{
+		Timeout: clientTimeout,
+		Transport: &roundTripper{
+			next:   transport,
+			config: config,
+			hosts:  make(map[string]chan struct{}),
+		},
+	}
+}
+
+type roundTripper struct {
+	next   http.RoundTripper
+	config Config
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{} // per host semaphore
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrip must not modify the caller's request
+	req = req.Clone(req.Context())
+	if rt.config.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", rt.config.UserAgent)
+	}
+
+	release, err := rt.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := rt.next.RoundTrip(req)
+		if attempt >= rt.config.MaxRetries || !retryable(req, resp, err) {
+			if err != nil {
+				release()
+				return nil, err
+			}
+			// the host slot is held until the caller is done with the body
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+			return resp, nil
+		}
+
+		wait := backoff(attempt, resp)
+		if err != nil {
+			log.Printf("Request to %s failed, retrying in %s: %v", req.URL.Host, wait, err)
+		} else {
+			log.Printf("Request to %s returned %d, retrying in %s", req.URL.Host, resp.StatusCode, wait)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleep(req.Context(), wait); err != nil {
+			release()
+			return nil, err
+		}
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				release()
+				return nil, fmt.Errorf("fail to rewind request body: %v", err)
+			}
+			req.Body = body
+		}
+	}
+}
+
+func (rt *roundTripper) acquire(ctx context.Context, host string) (func(), error) {
+	if rt.config.MaxPerHost <= 0 {
+		return func() {}, nil
+	}
+
+	rt.mu.Lock()
+	semaphore, ok := rt.hosts[host]
+	if !ok {
+		semaphore = make(chan struct{}, rt.config.MaxPerHost)
+		rt.hosts[host] = semaphore
+	}
+	rt.mu.Unlock()
+
+	select {
+	case semaphore <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-semaphore }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// retryable only retries requests that are safe to send twice, POSTs are never retried
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		// the caller gave up, retrying cannot help
+		return req.Context().Err() == nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// backoff is exponential with full jitter, a Retry-After in seconds is honoured up to maxBackoff
+func backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			return min(time.Duration(seconds)*time.Second, maxBackoff)
+		}
+	}
+
+	ceiling := min(baseBackoff<<attempt, maxBackoff)
+	return time.Duration(rand.Int64N(int64(ceiling)) + 1)
+}
+
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (body *releasingBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.release()
+	return err
+}
@@ -9,16 +9,19 @@ import (
 
 	"github.com/SC2006-Lab/MobileAppProject/api"
 	"github.com/SC2006-Lab/MobileAppProject/data"
-	"github.com/SC2006-Lab/MobileAppProject/middleware"
 	"github.com/SC2006-Lab/MobileAppProject/database"
+	"github.com/SC2006-Lab/MobileAppProject/middleware"
+	"github.com/SC2006-Lab/MobileAppProject/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
 func main() {
 
+	database.InitRedis()
 	apiData := data.NewApiData()
 	apiData.Init()
-	database.InitRedis()
+	apiData.StartCarParkRefresh(utils.GetEnvConfig().CARPARK_REFRESH_INTERVAL)
+	apiData.StartWeatherRefresh(utils.GetEnvConfig().WEATHER_REFRESH_INTERVAL)
 	server := middleware.NewServer()
 
 	api.SetupRoutes(server.App, apiData)
@@ -28,7 +31,11 @@ func main() {
 
 	go func() { server.Init() }()
 
-	defer func(){
+	defer func() {
+		apiData.StopCarParkRefresh()
+		apiData.StopWeatherRefresh()
+		apiData.OneMapToken.Stop()
+		apiData.URAToken.Stop()
 		database.CloseRedis()
 		log.Println("Server closed.")
 	}()
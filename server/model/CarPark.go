@@ -1,5 +1,28 @@
 package model
 
+import "strings"
+
+// Lot type codes used by LTA DataMall and HDB
+const (
+	LotTypeCar        = "C"
+	LotTypeMotorcycle = "Y"
+	LotTypeHeavy      = "H"
+	LotTypeLorry      = "L" // only reported by a few HDB car parks
+)
+
+// Vehicle types accepted by the API and the lot types they can park in
+const (
+	VehicleTypeCar        = "car"
+	VehicleTypeMotorcycle = "motorcycle"
+	VehicleTypeHeavy      = "heavy"
+)
+
+var VehicleLotTypes = map[string][]string{
+	VehicleTypeCar:        {LotTypeCar},
+	VehicleTypeMotorcycle: {LotTypeMotorcycle},
+	VehicleTypeHeavy:      {LotTypeHeavy, LotTypeLorry},
+}
+
 type CarPark struct {
 	CarParkID   string          `json:"carParkID"`
 	Address     string          `json:"address"`
@@ -7,11 +30,48 @@ type CarPark struct {
 	Latitude    float64         `json:"latitude"`
 	Longitude   float64         `json:"longitude"`
 	LotDetails  map[string]*Lot `json:"lotDetails"`
+
+	// HDB car park attributes, nil / empty when the upstream does not report them
+	GantryHeight        *float64 `json:"gantryHeight,omitempty"` // metres, 0 when there is no gantry
+	TypeOfParkingSystem string   `json:"typeOfParkingSystem,omitempty"`
+	ShortTermParking    string   `json:"shortTermParking,omitempty"`
+	FreeParking         string   `json:"freeParking,omitempty"` // e.g. "SUN & PH FR 7AM-10.30PM" or "NO"
+	NightParking        *bool    `json:"nightParking,omitempty"`
+	CarParkDecks        *int     `json:"carParkDecks,omitempty"`
+	CarParkBasement     *bool    `json:"carParkBasement,omitempty"`
+
+	// Parking charges keyed by lot type, nil when unknown
+	Rates map[string]*LotRates `json:"rates,omitempty"`
 }
 
 type Lot struct {
-	TotalLots     string `json:"totalLots"`
-	AvailableLots string `json:"availableLots"`
+	TotalLots      string `json:"totalLots"`
+	AvailableLots  string `json:"availableLots"`
+	UpdateDatetime string `json:"updateDatetime"` // upstream timestamp of AvailableLots
+}
+
+// Sheltered reports whether drivers stay out of the rain, i.e. a multi-storey, basement or covered car park.
+// It is nil when the car park type is unknown
+func (carPark *CarPark) Sheltered() *bool {
+	sheltered := true
+	if carPark.CarParkBasement != nil && *carPark.CarParkBasement {
+		return &sheltered
+	}
+	if carPark.CarParkType == "" {
+		return nil
+	}
+
+	carParkType := strings.ToUpper(carPark.CarParkType)
+	sheltered = strings.Contains(carParkType, "MULTI-STOREY") ||
+		strings.Contains(carParkType, "BASEMENT") ||
+		strings.Contains(carParkType, "COVERED")
+	return &sheltered
+}
+
+// IsSheltered is Sheltered with an unknown type counted as not sheltered
+func (carPark *CarPark) IsSheltered() bool {
+	sheltered := carPark.Sheltered()
+	return sheltered != nil && *sheltered
 }
 
 func NewCarPark() map[string]*CarPark {
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+const minutesPerDay = 24 * 60
+
+// Day types a rate band applies to, public holidays are charged like Sundays
+const (
+	DayTypeWeekday  = "weekday"
+	DayTypeSaturday = "saturday"
+	DayTypeSunday   = "sunday"
+	DayTypeAll      = "all"
+)
+
+// RateBand charges Rate for every started block of BlockMinutes between StartMinute and EndMinute.
+// Minutes are counted from midnight, a band with EndMinute <= StartMinute runs past midnight
+type RateBand struct {
+	DayType      string  `json:"dayType"`
+	StartMinute  int     `json:"startMinute"`
+	EndMinute    int     `json:"endMinute"`
+	Rate         float64 `json:"rate"` // SGD per block
+	BlockMinutes int     `json:"blockMinutes"`
+	Cap          float64 `json:"cap,omitempty"` // SGD per continuous stay in the band, 0 when uncapped
+}
+
+// LotRates is the charging scheme of one lot type, time not covered by any band is free
+type LotRates struct {
+	Source string     `json:"source"` // URA or HDB
+	Bands  []RateBand `json:"bands"`
+	// FreeParking is the HDB free parking schedule, e.g. "SUN & PH FR 7AM-10.30PM"
+	FreeParking string `json:"freeParking,omitempty"`
+}
+
+func dayTypeOf(t time.Time) string {
+	switch t.Weekday() {
+	case time.Saturday:
+		return DayTypeSaturday
+	case time.Sunday:
+		return DayTypeSunday
+	default:
+		return DayTypeWeekday
+	}
+}
+
+func (band *RateBand) covers(t time.Time) bool {
+	if band.DayType != DayTypeAll && band.DayType != dayTypeOf(t) {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if band.EndMinute > band.StartMinute {
+		return minute >= band.StartMinute && minute < band.EndMinute
+	}
+	// overnight band
+	return minute >= band.StartMinute || minute < band.EndMinute
+}
+
+// bandAt returns the band charging at t, nil when parking is free. freeWindow is nil when there is no free parking
+func (rates *LotRates) bandAt(t time.Time, freeWindow *FreeParkingWindow) *RateBand {
+	if freeWindow != nil && freeWindow.Covers(t) {
+		return nil
+	}
+
+	for i := range rates.Bands {
+		if rates.Bands[i].covers(t) {
+			return &rates.Bands[i]
+		}
+	}
+	return nil
+}
+
+// EstimateFee returns the parking charge (SGD) for a stay starting at arrival.
+// The stay is split into continuous segments per band, each segment pays per started block up to the band cap.
+// The stay is walked from one band or free parking boundary to the next, the band cannot change in between
+func (rates *LotRates) EstimateFee(arrival time.Time, duration time.Duration) float64 {
+	arrival = arrival.In(SingaporeTime).Truncate(time.Minute)
+	totalMinutes := int(math.Ceil(duration.Minutes()))
+
+	var freeWindow *FreeParkingWindow
+	if window, ok := ParseFreeParking(rates.FreeParking); ok {
+		freeWindow = &window
+	}
+
+	// minutes of the day at which the charging band may change, midnight aside
+	boundaries := make([]int, 0, 2*len(rates.Bands)+2)
+	for _, band := range rates.Bands {
+		boundaries = append(boundaries, band.StartMinute, band.EndMinute)
+	}
+	if freeWindow != nil {
+		boundaries = append(boundaries, freeWindow.StartMinute, freeWindow.EndMinute)
+	}
+
+	var fee float64
+	var segmentBand *RateBand
+	var segmentMinutes int
+
+	closeSegment := func() {
+		if segmentBand == nil || segmentMinutes == 0 {
+			return
+		}
+		blocks := math.Ceil(float64(segmentMinutes) / float64(max(segmentBand.BlockMinutes, 1)))
+		segmentFee := blocks * segmentBand.Rate
+		if segmentBand.Cap > 0 {
+			segmentFee = math.Min(segmentFee, segmentBand.Cap)
+		}
+		fee += segmentFee
+	}
+
+	for elapsed := 0; elapsed < totalMinutes; {
+		t := arrival.Add(time.Duration(elapsed) * time.Minute)
+		minute := t.Hour()*60 + t.Minute()
+
+		next := minutesPerDay
+		for _, boundary := range boundaries {
+			if boundary > minute && boundary < next {
+				next = boundary
+			}
+		}
+		span := min(next-minute, totalMinutes-elapsed)
+
+		band := rates.bandAt(t, freeWindow)
+		if band != segmentBand {
+			closeSegment()
+			segmentBand = band
+			segmentMinutes = 0
+		}
+		segmentMinutes += span
+		elapsed += span
+	}
+	closeSegment()
+
+	return math.Round(fee*100) / 100
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// rates shaped like an HDB central car park
+var testRates = &LotRates{
+	Source:      "HDB",
+	FreeParking: "SUN & PH FR 7AM-10.30PM",
+	Bands: []RateBand{
+		{DayType: DayTypeWeekday, StartMinute: 7 * 60, EndMinute: 17 * 60, Rate: 1.20, BlockMinutes: 30},
+		{DayType: DayTypeAll, StartMinute: 22*60 + 30, EndMinute: 7 * 60, Rate: 0.60, BlockMinutes: 30, Cap: 5.00},
+		{DayType: DayTypeAll, StartMinute: 0, EndMinute: 24 * 60, Rate: 0.60, BlockMinutes: 30},
+	},
+}
+
+func sgt(day, hour, minute int) time.Time {
+	// 3 January 2026 is a Saturday
+	return time.Date(2026, time.January, day, hour, minute, 0, 0, SingaporeTime)
+}
+
+func TestEstimateFee(t *testing.T) {
+	tests := []struct {
+		name     string
+		arrival  time.Time
+		duration time.Duration
+		want     float64
+	}{
+		{"within one band", sgt(5, 10, 0), time.Hour, 2.40},
+		{"started block is charged in full", sgt(5, 10, 0), 45 * time.Minute, 2.40},
+		{"crosses into the next band", sgt(5, 16, 30), time.Hour, 1.80},
+		{"band of another day type is skipped", sgt(3, 12, 0), time.Hour, 1.20},
+		{"night band is capped", sgt(5, 22, 30), 8*time.Hour + 30*time.Minute, 5.00},
+		{"cap applies per night", sgt(5, 22, 30), 32*time.Hour + 30*time.Minute, 40.60}, // 5.00 + 20 day blocks + 11 evening blocks + 5.00
+		{"free parking on Sunday", sgt(4, 10, 0), 2 * time.Hour, 0},
+		{"free parking ends", sgt(4, 21, 30), 2 * time.Hour, 1.20},
+		{"no duration", sgt(5, 10, 0), 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := testRates.EstimateFee(test.arrival, test.duration); got != test.want {
+				t.Errorf("EstimateFee(%s, %s) = %.2f, want %.2f", test.arrival, test.duration, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsFreeParkingAt(t *testing.T) {
+	tests := []struct {
+		schedule string
+		at       time.Time
+		want     bool
+	}{
+		{"SUN & PH FR 7AM-10.30PM", sgt(4, 7, 0), true},
+		{"SUN & PH FR 7AM-10.30PM", sgt(4, 22, 30), false},
+		{"SUN & PH FR 7AM-10.30PM", sgt(5, 12, 0), false},
+		{"sun & ph fr 1PM-10.30PM", sgt(4, 12, 59), false},
+		{"NO", sgt(4, 12, 0), false},
+		{"", sgt(4, 12, 0), false},
+	}
+
+	for _, test := range tests {
+		if got := IsFreeParkingAt(test.schedule, test.at); got != test.want {
+			t.Errorf("IsFreeParkingAt(%q, %s) = %t, want %t", test.schedule, test.at, got, test.want)
+		}
+	}
+}
@@ -5,3 +5,40 @@ type URA_Resp struct {
 	Message string `json:"message"`
 	Result  string `json:"result"`
 }
+
+type URA_CarParkAvai_Resp struct {
+	Status  string `json:"Status"`
+	Message string `json:"Message"`
+	Result  []struct {
+		CarparkNo     string `json:"carparkNo"`
+		LotType       string `json:"lotType"` // C, M (motorcycle) or H
+		LotsAvailable string `json:"lotsAvailable"`
+		Geometries    []struct {
+			Coordinates string `json:"coordinates"` // SVY21 "easting,northing"
+		} `json:"geometries"`
+	} `json:"Result"`
+}
+
+// One record per car park, vehicle category and charging band
+type URA_CarParkDetails_Resp struct {
+	Status  string `json:"Status"`
+	Message string `json:"Message"`
+	Result  []struct {
+		PPCode        string `json:"ppCode"`
+		PPName        string `json:"ppName"`
+		VehCat        string `json:"vehCat"` // Car, Motorcycle or Heavy Vehicle
+		ParkingSystem string `json:"parkingSystem"`
+		ParkCapacity  int    `json:"parkCapacity"`
+		StartTime     string `json:"startTime"`
+		EndTime       string `json:"endTime"`
+		WeekdayRate   string `json:"weekdayRate"`
+		WeekdayMin    string `json:"weekdayMin"`
+		SatdayRate    string `json:"satdayRate"`
+		SatdayMin     string `json:"satdayMin"`
+		SunPHRate     string `json:"sunPHRate"`
+		SunPHMin      string `json:"sunPHMin"`
+		Geometries    []struct {
+			Coordinates string `json:"coordinates"` // SVY21 "easting,northing"
+		} `json:"geometries"`
+	} `json:"Result"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+const (
+	AlertAbove = "above" // notify when the available lots rise to the threshold
+	AlertBelow = "below" // notify when the available lots drop to the threshold
+
+	AlertChannelWebhook = "webhook"
+	AlertChannelExpo    = "expo"
+)
+
+// AlertSubscription watches either one car park or every car park within a radius of a location
+type AlertSubscription struct {
+	ID           string    `json:"id"`
+	CarParkID    string    `json:"carParkID,omitempty"`
+	Latitude     float64   `json:"latitude,omitempty"`
+	Longitude    float64   `json:"longitude,omitempty"`
+	RadiusMeters float64   `json:"radiusMeters,omitempty"`
+	LotType      string    `json:"lotType"`
+	Condition    string    `json:"condition"`
+	Threshold    int       `json:"threshold"`
+	Channel      string    `json:"channel"`
+	Target       string    `json:"target"` // webhook URL or Expo push token
+	CreatedAt    time.Time `json:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+type AlertNotification struct {
+	SubscriptionID string    `json:"subscriptionID"`
+	CarParkID      string    `json:"carParkID"`
+	LotType        string    `json:"lotType"`
+	Condition      string    `json:"condition"`
+	Threshold      int       `json:"threshold"`
+	AvailableLots  int       `json:"availableLots"`
+	TotalLots      string    `json:"totalLots"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	TriggeredAt    time.Time `json:"triggeredAt"`
+}
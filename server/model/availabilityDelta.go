@@ -0,0 +1,13 @@
+package model
+
+// AvailabilityDelta is a change of one lot between two refreshes
+type AvailabilityDelta struct {
+	CarParkID      string  `json:"carParkID"`
+	LotType        string  `json:"lotType"`
+	TotalLots      string  `json:"totalLots"`
+	AvailableLots  string  `json:"availableLots"`
+	UpdateDatetime string  `json:"updateDatetime"`
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	Removed        bool    `json:"removed,omitempty"` // the lot is no longer reported
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// AvailabilitySample is the availability of one lot type of a car park at one refresh
+type AvailabilitySample struct {
+	Time          time.Time `json:"time"`
+	LotType       string    `json:"lotType"`
+	AvailableLots int       `json:"availableLots"`
+	TotalLots     int       `json:"totalLots"` // 0 when unknown
+}
+
+// AvailabilityPoint aggregates the samples of one lot type within a time bucket
+type AvailabilityPoint struct {
+	Time             time.Time `json:"time"` // start of the bucket
+	LotType          string    `json:"lotType"`
+	AvgAvailableLots float64   `json:"avgAvailableLots"`
+	MinAvailableLots int       `json:"minAvailableLots"`
+	MaxAvailableLots int       `json:"maxAvailableLots"`
+	TotalLots        int       `json:"totalLots"`
+	Samples          int       `json:"samples"`
+}
@@ -2,8 +2,10 @@ package model
 
 type OneMapRoute_Resp struct {
 	RouteGeometry string `json:"route_geometry"`
-	RouteSummary  struct {
+	// nil when OneMap found no route
+	RouteSummary *struct {
 		TotalDistance float64 `json:"total_distance"`
 		TotalTime     float64 `json:"total_time"`
 	} `json:"route_summary"`
+	Error string `json:"error"`
 }
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Singapore does not observe daylight saving, a fixed zone avoids depending on tzdata in the container
+var SingaporeTime = time.FixedZone("SGT", 8*60*60)
+
+// Matches the HDB free parking schedule, e.g. "SUN & PH FR 7AM-10.30PM"
+var freeParkingRegex = regexp.MustCompile(`^SUN & PH FR (\d{1,2}(?:\.\d{2})?)(AM|PM)-(\d{1,2}(?:\.\d{2})?)(AM|PM)$`)
+
+// FreeParkingWindow is a parsed HDB free parking schedule, minutes from midnight on Sundays
+type FreeParkingWindow struct {
+	StartMinute int
+	EndMinute   int
+}
+
+// ParseFreeParking parses the HDB free parking schedule, ok is false when there is none or it is not understood
+func ParseFreeParking(schedule string) (window FreeParkingWindow, ok bool) {
+	matches := freeParkingRegex.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(schedule)))
+	if matches == nil {
+		return FreeParkingWindow{}, false
+	}
+
+	start, okStart := parseClockTime(matches[1], matches[2])
+	end, okEnd := parseClockTime(matches[3], matches[4])
+	if !okStart || !okEnd {
+		return FreeParkingWindow{}, false
+	}
+	return FreeParkingWindow{StartMinute: start, EndMinute: end}, true
+}
+
+// Covers reports whether parking is free at t.
+// Public holidays are not known to the server, so only Sundays are matched
+func (window FreeParkingWindow) Covers(t time.Time) bool {
+	local := t.In(SingaporeTime)
+	if local.Weekday() != time.Sunday {
+		return false
+	}
+
+	minutes := local.Hour()*60 + local.Minute()
+	return minutes >= window.StartMinute && minutes < window.EndMinute
+}
+
+// IsFreeParkingAt reports whether the HDB free parking schedule applies at t
+func IsFreeParkingAt(schedule string, t time.Time) bool {
+	window, ok := ParseFreeParking(schedule)
+	return ok && window.Covers(t)
+}
+
+// parseClockTime turns "10.30" + "PM" into minutes after midnight
+func parseClockTime(clock, meridiem string) (int, bool) {
+	hourMinute := strings.SplitN(clock, ".", 2)
+	hour, err := strconv.Atoi(hourMinute[0])
+	if err != nil || hour < 1 || hour > 12 {
+		return 0, false
+	}
+
+	minute := 0
+	if len(hourMinute) == 2 {
+		if minute, err = strconv.Atoi(hourMinute[1]); err != nil || minute > 59 {
+			return 0, false
+		}
+	}
+
+	hour %= 12
+	if meridiem == "PM" {
+		hour += 12
+	}
+	return hour*60 + minute, true
+}
@@ -5,3 +5,9 @@ type RouteInfo struct {
 	Duration float64 `json:"duration"`
 	Polyline string  `json:"polyline"`
 }
+
+// Route status reported next to every routed result
+const (
+	RouteStatusOK        = "ok"        // driving route from OneMap
+	RouteStatusEstimated = "estimated" // OneMap failed, straight-line distance and an estimated duration
+)
@@ -1,12 +1,31 @@
 package model
 
+import "strings"
+
+// NEA forecast wordings that mean rain, e.g. "Light Rain", "Passing Showers", "Heavy Thundery Showers with Gusty Winds"
+var rainForecastKeywords = []string{"RAIN", "SHOWER", "DRIZZLE", "THUNDER"}
+
 type WeatherAreaInfo struct {
-	Name      string
-	Latitude  float64
-	Longitude float64
-	Weather   string
+	Name            string  `json:"name"`
+	Latitude        float64 `json:"latitude"`
+	Longitude       float64 `json:"longitude"`
+	Weather         string  `json:"forecast"`
+	ValidFrom       string  `json:"validFrom"`
+	ValidTo         string  `json:"validTo"`
+	UpdateTimestamp string  `json:"updateTimestamp"`
 }
 
 func NewWeatherAreaInfo() map[string]*WeatherAreaInfo {
 	return make(map[string]*WeatherAreaInfo)
 }
+
+// IsRaining reports whether the forecast of the area is any kind of rain
+func (info *WeatherAreaInfo) IsRaining() bool {
+	forecast := strings.ToUpper(info.Weather)
+	for _, keyword := range rainForecastKeywords {
+		if strings.Contains(forecast, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,21 +3,44 @@ package utils
 import (
 	"log"
 	"sync"
+	"time"
 
 	"github.com/caarlos0/env"
 	"github.com/joho/godotenv"
 )
 
 type EnvConfig struct {
-	PORT            string `env:"PORT,required"`
-	LTA_ACCOUNT_KEY string `env:"LTA_ACCOUNT_KEY,required"`
-	URA_ACCESS_KEY  string `env:"URA_ACCESS_KEY,required"`
-	ONEMAP_EMAIL    string `env:"ONEMAP_EMAIL,required"`
-	ONEMAP_PASSWORD string `env:"ONEMAP_PASSWORD,required"`
-	REDIS_ADDRESS   string `env:"REDIS_ADDRESS,required"`
-	REDIS_PASSWORD  string `env:"REDIS_PASSWORD,required"`
-	REDIS_DB        int    `env:"REDIS_DB,required"`
-	REDIS_PORT      string `env:"REDIS_PORT,required"`
+	PORT                 string        `env:"PORT,required"`
+	LTA_ACCOUNT_KEY      string        `env:"LTA_ACCOUNT_KEY,required"`
+	URA_ACCESS_KEY       string        `env:"URA_ACCESS_KEY,required"`
+	ONEMAP_EMAIL         string        `env:"ONEMAP_EMAIL,required"`
+	ONEMAP_PASSWORD      string        `env:"ONEMAP_PASSWORD,required"`
+	REDIS_ADDRESS        string        `env:"REDIS_ADDRESS,required"`
+	REDIS_PASSWORD       string        `env:"REDIS_PASSWORD,required"`
+	REDIS_DB             int           `env:"REDIS_DB,required"`
+	REDIS_PORT           string        `env:"REDIS_PORT,required"`
+	REDIS_NAMESPACE      string        `env:"REDIS_NAMESPACE" envDefault:"carpark"`
+	MEMORY_CACHE_SIZE    int           `env:"MEMORY_CACHE_SIZE" envDefault:"10000"`
+	CACHE_RETRY_INTERVAL time.Duration `env:"CACHE_RETRY_INTERVAL" envDefault:"30s"`
+	CACHE_CALL_TIMEOUT   time.Duration `env:"CACHE_CALL_TIMEOUT" envDefault:"500ms"`
+
+	CARPARK_REFRESH_INTERVAL time.Duration `env:"CARPARK_REFRESH_INTERVAL" envDefault:"5m"`
+	WEATHER_REFRESH_INTERVAL time.Duration `env:"WEATHER_REFRESH_INTERVAL" envDefault:"30m"`
+	ROUTE_CACHE_TTL          time.Duration `env:"ROUTE_CACHE_TTL" envDefault:"15m"`
+	NEARBY_REQUEST_TIMEOUT   time.Duration `env:"NEARBY_REQUEST_TIMEOUT" envDefault:"20s"`
+	ROUTE_CALL_TIMEOUT       time.Duration `env:"ROUTE_CALL_TIMEOUT" envDefault:"5s"`
+	UPSTREAM_CALL_TIMEOUT    time.Duration `env:"UPSTREAM_CALL_TIMEOUT" envDefault:"30s"`
+	HTTP_USER_AGENT          string        `env:"HTTP_USER_AGENT" envDefault:"SC2006-MobileAppProject-Server"`
+	HTTP_MAX_PER_HOST        int           `env:"HTTP_MAX_PER_HOST" envDefault:"16"`
+	HTTP_MAX_RETRIES         int           `env:"HTTP_MAX_RETRIES" envDefault:"2"`
+	SNAPSHOT_FILE            string        `env:"SNAPSHOT_FILE" envDefault:"snapshot_data/apidata.json.gz"`
+	SNAPSHOT_MAX_AGE         time.Duration `env:"SNAPSHOT_MAX_AGE" envDefault:"24h"`
+	HISTORY_DIR              string        `env:"HISTORY_DIR" envDefault:"history_data"`
+	HISTORY_RETENTION        time.Duration `env:"HISTORY_RETENTION" envDefault:"672h"`
+	ALERT_NOTIFIER           string        `env:"ALERT_NOTIFIER"`
+	ALERT_CREATE_LIMIT       int           `env:"ALERT_CREATE_LIMIT" envDefault:"20"`
+	EXPO_PUSH_URL            string        `env:"EXPO_PUSH_URL" envDefault:"https://exp.host/--/api/v2/push/send"`
+	EXPO_ACCESS_TOKEN        string        `env:"EXPO_ACCESS_TOKEN"`
 }
 
 var (